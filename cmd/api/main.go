@@ -14,6 +14,7 @@ import (
 	"github.com/kokuroshesh/bugvay/internal/config"
 	"github.com/kokuroshesh/bugvay/internal/database"
 	"github.com/kokuroshesh/bugvay/internal/queue"
+	_ "github.com/kokuroshesh/bugvay/internal/scanners/all" // register scanner modules
 )
 
 func main() {
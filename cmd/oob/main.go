@@ -0,0 +1,56 @@
+package main
+
+import (
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/kokuroshesh/bugvay/internal/config"
+	"github.com/kokuroshesh/bugvay/internal/database"
+	"github.com/kokuroshesh/bugvay/internal/oob"
+	"github.com/kokuroshesh/bugvay/internal/services"
+)
+
+func main() {
+	// Load config
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	// Workers build callback URLs from the public host
+	if err := cfg.OOB.Validate(); err != nil {
+		log.Fatalf("Invalid interaction server config: %v", err)
+	}
+
+	// Connect to Postgres: issued tokens and interactions live there
+	pg, err := database.NewPostgres(&cfg.Postgres)
+	if err != nil {
+		log.Fatalf("Failed to connect to Postgres: %v", err)
+	}
+	defer pg.Close()
+	log.Println("✓ Connected to PostgreSQL")
+
+	// Start listeners
+	srv := oob.NewServer(cfg.OOB, services.NewInteractionService(pg))
+	if err := srv.Start(); err != nil {
+		log.Fatalf("Failed to start interaction server: %v", err)
+	}
+	defer srv.Close()
+
+	log.Printf("📡 Interaction server listening: http=%s", srv.HTTPAddr())
+	if cfg.OOB.DNSAddr != "" {
+		log.Printf("   dns=%s domain=%s", cfg.OOB.DNSAddr, cfg.OOB.Domain)
+	}
+	if cfg.OOB.SMTPAddr != "" {
+		log.Printf("   smtp=%s", cfg.OOB.SMTPAddr)
+	}
+
+	// Wait for interrupt signal
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	log.Println("Shutting down interaction server...")
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/kokuroshesh/bugvay/internal/config"
 	"github.com/kokuroshesh/bugvay/internal/database"
+	_ "github.com/kokuroshesh/bugvay/internal/scanners/all" // register scanner modules
 	"github.com/kokuroshesh/bugvay/internal/worker"
 )
 
@@ -36,7 +37,10 @@ func main() {
 	}
 
 	// Initialize worker
-	w := worker.NewWorker(cfg, pg, ch)
+	w, err := worker.NewWorker(cfg, pg, ch)
+	if err != nil {
+		log.Fatalf("Failed to start worker: %v", err)
+	}
 	defer w.Shutdown()
 
 	// Start worker
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kokuroshesh/bugvay/internal/services"
+)
+
+func VulnerabilityRate(service *services.AnalyticsService) gin.HandlerFunc {
+	return analyticsHandler(func(ctx context.Context, f *services.AnalyticsFilter) (interface{}, error) {
+		return service.VulnerabilityRate(ctx, f)
+	})
+}
+
+func ResponseTimes(service *services.AnalyticsService) gin.HandlerFunc {
+	return analyticsHandler(func(ctx context.Context, f *services.AnalyticsFilter) (interface{}, error) {
+		return service.ResponseTimes(ctx, f)
+	})
+}
+
+func SlowestHosts(service *services.AnalyticsService) gin.HandlerFunc {
+	return analyticsHandler(func(ctx context.Context, f *services.AnalyticsFilter) (interface{}, error) {
+		return service.SlowestHosts(ctx, f)
+	})
+}
+
+func TopReflectedParams(service *services.AnalyticsService) gin.HandlerFunc {
+	return analyticsHandler(func(ctx context.Context, f *services.AnalyticsFilter) (interface{}, error) {
+		return service.TopReflectedParams(ctx, f)
+	})
+}
+
+// analyticsHandler parses the shared filter query parameters:
+// program_id, scanner, from, to (RFC3339), bucket and limit
+func analyticsHandler(query func(ctx context.Context, f *services.AnalyticsFilter) (interface{}, error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		programID, _ := strconv.Atoi(c.Query("program_id"))
+		limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+		f := &services.AnalyticsFilter{
+			ProgramID: programID,
+			Scanner:   c.Query("scanner"),
+			Bucket:    c.DefaultQuery("bucket", "hour"),
+			Limit:     limit,
+		}
+
+		var err error
+		if v := c.Query("from"); v != "" {
+			if f.From, err = time.Parse(time.RFC3339, v); err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid from (RFC3339 expected)"})
+				return
+			}
+		}
+		if v := c.Query("to"); v != "" {
+			if f.To, err = time.Parse(time.RFC3339, v); err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid to (RFC3339 expected)"})
+				return
+			}
+		}
+
+		data, err := query(c.Request.Context(), f)
+		switch {
+		case errors.Is(err, services.ErrAnalyticsUnavailable):
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+			return
+		case errors.Is(err, services.ErrInvalidBucket):
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		case err != nil:
+			c.Error(err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"data": data})
+	}
+}
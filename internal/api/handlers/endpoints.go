@@ -2,11 +2,13 @@ package handlers
 
 import (
 	"bufio"
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/kokuroshesh/bugvay/internal/scope"
 	"github.com/kokuroshesh/bugvay/internal/services"
 )
 
@@ -39,7 +41,7 @@ func UploadEndpoints(service *services.EndpointService) gin.HandlerFunc {
 				continue
 			}
 
-			_, err := service.CreateEndpoint(c.Request.Context(), req.AssetID, line, "upload")
+			_, err := service.CreateEndpoint(c.Request.Context(), req.AssetID, services.EndpointRequest{URL: line}, "upload")
 			if err != nil {
 				skipped++
 				continue
@@ -55,6 +57,35 @@ func UploadEndpoints(service *services.EndpointService) gin.HandlerFunc {
 	}
 }
 
+type CreateEndpointRequest struct {
+	AssetID int `json:"asset_id" binding:"required"`
+	services.EndpointRequest
+}
+
+// CreateEndpoint stores one endpoint with the request it is scanned with,
+// for requests a URL list cannot express (POST bodies, headers)
+func CreateEndpoint(service *services.EndpointService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req CreateEndpointRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.Error(err)
+			return
+		}
+
+		endpoint, err := service.CreateEndpoint(c.Request.Context(), req.AssetID, req.EndpointRequest, "api")
+		switch {
+		case errors.Is(err, services.ErrInvalidEndpoint), errors.Is(err, scope.ErrOutOfScope):
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		case err != nil:
+			c.Error(err)
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{"data": endpoint})
+	}
+}
+
 func ListEndpoints(service *services.EndpointService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		assetID, _ := strconv.Atoi(c.Query("asset_id"))
@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/kokuroshesh/bugvay/internal/queue"
@@ -9,23 +11,36 @@ import (
 
 func ListJobs(client *queue.Client) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// TODO: Implement Asynq inspector to list jobs
-		c.JSON(http.StatusOK, gin.H{"data": []interface{}{}})
+		state := c.DefaultQuery("state", queue.StatePending)
+		page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+		size, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+
+		jobs, err := client.ListJobs(c.Query("queue"), state, page, size)
+		if errors.Is(err, queue.ErrInvalidJobState) {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"data": jobs})
 	}
 }
 
 func GetJobStatus(client *queue.Client) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		jobID := c.Param("id")
-		status, err := client.GetJobStatus(c.Request.Context(), jobID)
+		job, err := client.GetJob(c.Param("id"))
+		if errors.Is(err, queue.ErrJobNotFound) {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "job not found"})
+			return
+		}
 		if err != nil {
 			c.Error(err)
 			return
 		}
 
-		c.JSON(http.StatusOK, gin.H{
-			"job_id": jobID,
-			"status": status,
-		})
+		c.JSON(http.StatusOK, gin.H{"data": job})
 	}
 }
@@ -1,10 +1,12 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/kokuroshesh/bugvay/internal/httpclient"
 	"github.com/kokuroshesh/bugvay/internal/services"
 )
 
@@ -19,6 +21,9 @@ func ListPrograms(service *services.ProgramService) gin.HandlerFunc {
 			return
 		}
 
+		for i := range programs {
+			redact(&programs[i])
+		}
 		c.JSON(http.StatusOK, gin.H{"data": programs})
 	}
 }
@@ -32,11 +37,16 @@ func CreateProgram(service *services.ProgramService) gin.HandlerFunc {
 		}
 
 		program, err := service.CreateProgram(c.Request.Context(), &req)
+		if errors.Is(err, httpclient.ErrInvalidProfile) || errors.Is(err, services.ErrInvalidRateLimit) {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		if err != nil {
 			c.Error(err)
 			return
 		}
 
+		redact(program)
 		c.JSON(http.StatusCreated, gin.H{"data": program})
 	}
 }
@@ -50,11 +60,93 @@ func GetProgram(service *services.ProgramService) gin.HandlerFunc {
 		}
 
 		program, err := service.GetProgram(c.Request.Context(), id)
+		if errors.Is(err, services.ErrProgramNotFound) {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		redact(program)
+		c.JSON(http.StatusOK, gin.H{"data": program})
+	}
+}
+
+// UpdateRequestProfile replaces the headers, cookies and credentials sent
+// with the program's probes. An empty body removes the profile.
+func UpdateRequestProfile(service *services.ProgramService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+
+		var profile *httpclient.Profile
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&profile); err != nil {
+				c.Error(err)
+				return
+			}
+		}
+
+		program, err := service.SetRequestProfile(c.Request.Context(), id, profile)
+		if errors.Is(err, httpclient.ErrInvalidProfile) {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, services.ErrProgramNotFound) {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
 		if err != nil {
 			c.Error(err)
 			return
 		}
 
+		redact(program)
 		c.JSON(http.StatusOK, gin.H{"data": program})
 	}
 }
+
+// UpdateRateLimit sets the program's per-host request rate; 0 falls back
+// to the worker default
+func UpdateRateLimit(service *services.ProgramService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+
+		var req services.RateLimitRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.Error(err)
+			return
+		}
+
+		program, err := service.SetRateLimit(c.Request.Context(), id, &req)
+		if errors.Is(err, services.ErrInvalidRateLimit) {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, services.ErrProgramNotFound) {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		redact(program)
+		c.JSON(http.StatusOK, gin.H{"data": program})
+	}
+}
+
+// redact keeps profile credentials out of API responses
+func redact(p *services.Program) {
+	p.RequestProfile = p.RequestProfile.Redacted()
+}
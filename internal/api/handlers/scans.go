@@ -1,7 +1,10 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/kokuroshesh/bugvay/internal/services"
@@ -16,6 +19,10 @@ func CreateScan(service *services.ScanService) gin.HandlerFunc {
 		}
 
 		scan, err := service.CreateScan(c.Request.Context(), &req)
+		if errors.Is(err, services.ErrInvalidScan) {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		if err != nil {
 			c.Error(err)
 			return
@@ -27,7 +34,22 @@ func CreateScan(service *services.ScanService) gin.HandlerFunc {
 
 func ListScans(service *services.ScanService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{"data": []interface{}{}})
+		programID, _ := strconv.Atoi(c.Query("program_id"))
+		filters := map[string]interface{}{
+			"status":     c.Query("status"),
+			"program_id": programID,
+		}
+
+		limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+		offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+		scans, err := service.ListScans(c.Request.Context(), filters, limit, offset)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"data": scans})
 	}
 }
 
@@ -35,6 +57,42 @@ func GetScan(service *services.ScanService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		id := c.Param("id")
 		scan, err := service.GetScanStatus(c.Request.Context(), id)
+		if errors.Is(err, services.ErrScanNotFound) {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"data": scan})
+	}
+}
+
+func CancelScan(service *services.ScanService) gin.HandlerFunc {
+	return scanAction(service.CancelScan)
+}
+
+func PauseScan(service *services.ScanService) gin.HandlerFunc {
+	return scanAction(service.PauseScan)
+}
+
+func ResumeScan(service *services.ScanService) gin.HandlerFunc {
+	return scanAction(service.ResumeScan)
+}
+
+func scanAction(action func(ctx context.Context, scanID string) (*services.Scan, error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scan, err := action(c.Request.Context(), c.Param("id"))
+		if errors.Is(err, services.ErrScanNotFound) {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, services.ErrScanStateConflict) {
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		if err != nil {
 			c.Error(err)
 			return
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kokuroshesh/bugvay/internal/scope"
+	"github.com/kokuroshesh/bugvay/internal/services"
+)
+
+func ListScopeRules(service *services.ScopeService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		programID, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+
+		rules, err := service.ListRules(c.Request.Context(), programID)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"data": rules})
+	}
+}
+
+func CreateScopeRule(service *services.ScopeService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		programID, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+
+		var req services.CreateScopeRuleRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.Error(err)
+			return
+		}
+
+		rule, err := service.CreateRule(c.Request.Context(), programID, &req)
+		if errors.Is(err, scope.ErrInvalidRule) {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.Error(err)
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{"data": rule})
+	}
+}
+
+func DeleteScopeRule(service *services.ScopeService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		programID, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+			return
+		}
+		ruleID, err := strconv.Atoi(c.Param("rule_id"))
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid rule id"})
+			return
+		}
+
+		if err := service.DeleteRule(c.Request.Context(), programID, ruleID); err != nil {
+			c.Error(err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "scope rule deleted"})
+	}
+}
@@ -45,6 +45,8 @@ func NewRouter(pg *database.PostgresDB, ch *database.ClickHouseDB, q *queue.Clie
 	findingService := services.NewFindingService(pg, ch)
 	programService := services.NewProgramService(pg)
 	assetService := services.NewAssetService(pg)
+	scopeService := services.NewScopeService(pg)
+	analyticsService := services.NewAnalyticsService(ch)
 
 	// API v1 routes
 	v1 := r.Group("/api/v1")
@@ -55,6 +57,11 @@ func NewRouter(pg *database.PostgresDB, ch *database.ClickHouseDB, q *queue.Clie
 			programs.GET("", handlers.ListPrograms(programService))
 			programs.POST("", handlers.CreateProgram(programService))
 			programs.GET("/:id", handlers.GetProgram(programService))
+			programs.PUT("/:id/profile", handlers.UpdateRequestProfile(programService))
+			programs.PUT("/:id/rate-limit", handlers.UpdateRateLimit(programService))
+			programs.GET("/:id/scope", handlers.ListScopeRules(scopeService))
+			programs.POST("/:id/scope", handlers.CreateScopeRule(scopeService))
+			programs.DELETE("/:id/scope/:rule_id", handlers.DeleteScopeRule(scopeService))
 		}
 
 		// Assets
@@ -69,6 +76,7 @@ func NewRouter(pg *database.PostgresDB, ch *database.ClickHouseDB, q *queue.Clie
 		// Endpoints
 		endpoints := v1.Group("/endpoints")
 		{
+			endpoints.POST("", handlers.CreateEndpoint(endpointService))
 			endpoints.POST("/upload", handlers.UploadEndpoints(endpointService))
 			endpoints.GET("", handlers.ListEndpoints(endpointService))
 			endpoints.GET("/:id", handlers.GetEndpoint(endpointService))
@@ -80,6 +88,9 @@ func NewRouter(pg *database.PostgresDB, ch *database.ClickHouseDB, q *queue.Clie
 			scans.POST("", handlers.CreateScan(scanService))
 			scans.GET("", handlers.ListScans(scanService))
 			scans.GET("/:id", handlers.GetScan(scanService))
+			scans.POST("/:id/cancel", handlers.CancelScan(scanService))
+			scans.POST("/:id/pause", handlers.PauseScan(scanService))
+			scans.POST("/:id/resume", handlers.ResumeScan(scanService))
 		}
 
 		// Findings
@@ -90,6 +101,15 @@ func NewRouter(pg *database.PostgresDB, ch *database.ClickHouseDB, q *queue.Clie
 			findings.PATCH("/:id/triage", handlers.TriageFinding(findingService))
 		}
 
+		// Analytics (ClickHouse, optional)
+		analytics := v1.Group("/analytics")
+		{
+			analytics.GET("/vulnerability-rate", handlers.VulnerabilityRate(analyticsService))
+			analytics.GET("/response-times", handlers.ResponseTimes(analyticsService))
+			analytics.GET("/slowest-hosts", handlers.SlowestHosts(analyticsService))
+			analytics.GET("/reflected-params", handlers.TopReflectedParams(analyticsService))
+		}
+
 		// Jobs (Asynq status)
 		jobs := v1.Group("/jobs")
 		{
@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"net"
 	"os"
 
 	"github.com/spf13/viper"
@@ -14,6 +15,7 @@ type Config struct {
 	ClickHouse ClickHouseConfig
 	Worker     WorkerConfig
 	Scanner    ScannerConfig
+	OOB        OOBConfig
 }
 
 type APIConfig struct {
@@ -58,9 +60,42 @@ type ScannerConfig struct {
 	MaxRetries int
 }
 
+// OOBConfig is the out-of-band interaction service. cmd/oob runs the
+// listeners; workers hand out tokens for it when PublicHost is set, or run
+// the listeners themselves when Embedded.
+type OOBConfig struct {
+	HTTPAddr   string // listen address for HTTP callbacks
+	DNSAddr    string // listen address for DNS queries, optional
+	SMTPAddr   string // listen address for SMTP, optional
+	PublicHost string // host[:port] targets use to reach HTTPAddr
+	PublicIP   string // answer for A queries under Domain
+	Domain     string // zone delegated to the DNS listener, optional
+	Wait       int    // seconds to wait for callbacks after probing
+	Embedded   bool   // worker runs the listeners in-process
+}
+
+// Validate rejects an interaction service that hands out callback URLs
+// nothing can reach: without a public host, or with a wildcard listener
+// address in its place
+func (c OOBConfig) Validate() error {
+	host := c.PublicHost
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if host == "" {
+		return fmt.Errorf("OOB_PUBLIC_HOST is required: the host[:port] targets reach %s at", c.HTTPAddr)
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsUnspecified() {
+		return fmt.Errorf("OOB_PUBLIC_HOST %s is a listen address, not one targets can reach", c.PublicHost)
+	}
+	return nil
+}
+
 func Load() (*Config, error) {
 	viper.SetConfigFile(".env")
 	viper.AutomaticEnv()
+	viper.SetDefault("SCANNER_MAX_RETRIES", 3)
+	viper.SetDefault("OOB_WAIT", 10)
 
 	// Read .env if exists
 	if err := viper.ReadInConfig(); err != nil {
@@ -106,6 +141,16 @@ func Load() (*Config, error) {
 			Timeout:    viper.GetInt("SCANNER_TIMEOUT"),
 			MaxRetries: viper.GetInt("SCANNER_MAX_RETRIES"),
 		},
+		OOB: OOBConfig{
+			HTTPAddr:   getEnv("OOB_HTTP_ADDR", ":8090"),
+			DNSAddr:    getEnv("OOB_DNS_ADDR", ""),
+			SMTPAddr:   getEnv("OOB_SMTP_ADDR", ""),
+			PublicHost: getEnv("OOB_PUBLIC_HOST", ""),
+			PublicIP:   getEnv("OOB_PUBLIC_IP", ""),
+			Domain:     getEnv("OOB_DOMAIN", ""),
+			Wait:       viper.GetInt("OOB_WAIT"),
+			Embedded:   viper.GetBool("OOB_EMBEDDED"),
+		},
 	}
 
 	return config, nil
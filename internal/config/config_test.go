@@ -0,0 +1,24 @@
+package config
+
+import "testing"
+
+func TestOOBConfigValidate(t *testing.T) {
+	tests := []struct {
+		publicHost string
+		ok         bool
+	}{
+		{"203.0.113.10:8090", true},
+		{"oob.example.com", true},
+		{"[2001:db8::1]:8090", true},
+		{"", false},
+		{":8090", false},
+		{"0.0.0.0:8090", false},
+		{"[::]:8090", false},
+	}
+	for _, tt := range tests {
+		err := OOBConfig{HTTPAddr: ":8090", PublicHost: tt.publicHost}.Validate()
+		if (err == nil) != tt.ok {
+			t.Errorf("Validate(%q) = %v, want ok=%v", tt.publicHost, err, tt.ok)
+		}
+	}
+}
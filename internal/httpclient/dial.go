@@ -0,0 +1,41 @@
+package httpclient
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/kokuroshesh/bugvay/internal/scope"
+)
+
+// newTransport is http.DefaultTransport with scope enforced on every dial
+func newTransport() *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.DialContext = dialContext
+	return t
+}
+
+// dialContext dials like the default transport. With a scope in ctx, each
+// address is checked right before connecting, on the IP the name actually
+// resolved to for this connection.
+func dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	d := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+
+	if sc, ok := scope.FromContext(ctx); ok {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		d.Control = func(network, address string, _ syscall.RawConn) error {
+			ip, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			return sc.CheckAddr(host, net.ParseIP(ip))
+		}
+	}
+
+	return d.DialContext(ctx, network, addr)
+}
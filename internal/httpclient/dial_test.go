@@ -0,0 +1,68 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/kokuroshesh/bugvay/internal/scope"
+)
+
+func TestDialEnforcesScope(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			c.Close()
+		}
+	}()
+	_, port, _ := net.SplitHostPort(ln.Addr().String())
+
+	tests := []struct {
+		name  string
+		rules []scope.Rule
+		addr  string
+		ok    bool
+	}{
+		{"no scope", nil, "127.0.0.1:" + port, true},
+		{"in range", []scope.Rule{{Kind: scope.InScope, Type: scope.TypeCIDR, Pattern: "127.0.0.0/8"}}, "127.0.0.1:" + port, true},
+		{"outside ranges", []scope.Rule{{Kind: scope.InScope, Type: scope.TypeCIDR, Pattern: "10.0.0.0/8"}}, "127.0.0.1:" + port, false},
+		// The name is in scope, but what it resolves to at dial time is not
+		{"resolved into excluded range", []scope.Rule{
+			{Kind: scope.InScope, Type: scope.TypeHost, Pattern: "localhost"},
+			{Kind: scope.OutOfScope, Type: scope.TypeCIDR, Pattern: "127.0.0.0/8"},
+			{Kind: scope.OutOfScope, Type: scope.TypeCIDR, Pattern: "::1/128"},
+		}, "localhost:" + port, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.rules != nil {
+				sc, err := scope.New(1, tt.rules)
+				if err != nil {
+					t.Fatal(err)
+				}
+				ctx = scope.WithContext(ctx, sc)
+			}
+
+			conn, err := dialContext(ctx, "tcp", tt.addr)
+			if conn != nil {
+				conn.Close()
+			}
+			if tt.ok && err != nil {
+				t.Errorf("dial = %v, want connected", err)
+			}
+			if !tt.ok && !errors.Is(err, scope.ErrOutOfScope) {
+				t.Errorf("dial = %v, want ErrOutOfScope", err)
+			}
+		})
+	}
+}
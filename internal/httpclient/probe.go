@@ -0,0 +1,45 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Probe describes one request sent to a target and what came back
+type Probe struct {
+	Method          string
+	URL             string
+	Payload         string
+	RequestHeaders  http.Header
+	Status          int
+	ContentLength   int
+	Latency         time.Duration
+	Body            []byte
+	ResponseHeaders http.Header
+	Err             error
+}
+
+// ProbeRecorder receives every probe the scanner sends. Implementations
+// must not block.
+type ProbeRecorder interface {
+	RecordProbe(ctx context.Context, p *Probe)
+}
+
+// SetRecorder makes the scanner report every request to r
+func (s *Scanner) SetRecorder(r ProbeRecorder) {
+	s.recorder = r
+}
+
+type payloadKey struct{}
+
+// WithPayload tags requests made with ctx with the payload under test, so
+// recorded probes say what was injected
+func WithPayload(ctx context.Context, payload string) context.Context {
+	return context.WithValue(ctx, payloadKey{}, payload)
+}
+
+func payloadFrom(ctx context.Context) string {
+	p, _ := ctx.Value(payloadKey{}).(string)
+	return p
+}
@@ -0,0 +1,150 @@
+package httpclient
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// redacted replaces profile secrets in recorded probes and stored proofs
+const redacted = "[redacted]"
+
+// ErrInvalidProfile is returned by Profile.Validate
+var ErrInvalidProfile = errors.New("invalid request profile")
+
+// Profile is the identity a program's probes are sent with: identification
+// headers the program requires and credentials for authenticated areas.
+// Values the scanner sets itself, such as an injected header, win.
+type Profile struct {
+	Headers     map[string]string `json:"headers,omitempty"`
+	Cookies     map[string]string `json:"cookies,omitempty"`
+	BearerToken string            `json:"bearer_token,omitempty"`
+	BasicAuth   *BasicAuth        `json:"basic_auth,omitempty"`
+}
+
+type BasicAuth struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// Validate rejects header and cookie names that would corrupt requests
+func (p *Profile) Validate() error {
+	if p == nil {
+		return nil
+	}
+	for name, value := range p.Headers {
+		if name == "" || strings.ContainsAny(name, ":\r\n ") || strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("%w: header %q", ErrInvalidProfile, name)
+		}
+	}
+	for name, value := range p.Cookies {
+		if name == "" || strings.ContainsAny(name, "=; \r\n") || strings.ContainsAny(value, ";\r\n") {
+			return fmt.Errorf("%w: cookie %q", ErrInvalidProfile, name)
+		}
+	}
+	if p.BearerToken != "" && p.BasicAuth != nil {
+		return fmt.Errorf("%w: bearer_token and basic_auth are exclusive", ErrInvalidProfile)
+	}
+	return nil
+}
+
+// Redacted returns a copy with credentials masked, for API responses.
+// Header values are masked too: custom auth headers (X-Api-Key, ...) are
+// indistinguishable from identification headers.
+func (p *Profile) Redacted() *Profile {
+	if p == nil {
+		return nil
+	}
+
+	r := &Profile{}
+	if len(p.Headers) > 0 {
+		r.Headers = make(map[string]string, len(p.Headers))
+		for name := range p.Headers {
+			r.Headers[name] = redacted
+		}
+	}
+	if len(p.Cookies) > 0 {
+		r.Cookies = make(map[string]string, len(p.Cookies))
+		for name := range p.Cookies {
+			r.Cookies[name] = redacted
+		}
+	}
+	if p.BearerToken != "" {
+		r.BearerToken = redacted
+	}
+	if p.BasicAuth != nil {
+		r.BasicAuth = &BasicAuth{Username: p.BasicAuth.Username, Password: redacted}
+	}
+	return r
+}
+
+// apply fills in what req does not already set. With redact, every value
+// the profile supplies is replaced so the request can be recorded.
+func (p *Profile) apply(req *http.Request, redact bool) {
+	if p == nil {
+		return
+	}
+
+	for name, value := range p.Headers {
+		if req.Header.Get(name) == "" {
+			if redact {
+				value = redacted
+			}
+			req.Header.Set(name, value)
+		}
+	}
+
+	if len(p.Cookies) > 0 {
+		present := make(map[string]bool)
+		for _, c := range req.Cookies() {
+			present[c.Name] = true
+		}
+		var add []string
+		for name, value := range p.Cookies {
+			if present[name] {
+				continue
+			}
+			if redact {
+				value = redacted
+			}
+			add = append(add, name+"="+value)
+		}
+		if len(add) > 0 {
+			cookie := strings.Join(add, "; ")
+			if existing := req.Header.Get("Cookie"); existing != "" {
+				cookie = existing + "; " + cookie
+			}
+			req.Header.Set("Cookie", cookie)
+		}
+	}
+
+	if req.Header.Get("Authorization") != "" {
+		return
+	}
+	switch {
+	case p.BearerToken != "" && redact:
+		req.Header.Set("Authorization", "Bearer "+redacted)
+	case p.BearerToken != "":
+		req.Header.Set("Authorization", "Bearer "+p.BearerToken)
+	case p.BasicAuth != nil && redact:
+		req.Header.Set("Authorization", "Basic "+redacted)
+	case p.BasicAuth != nil:
+		creds := p.BasicAuth.Username + ":" + p.BasicAuth.Password
+		req.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(creds)))
+	}
+}
+
+type profileKey struct{}
+
+// WithProfile makes every request sent with ctx carry p
+func WithProfile(ctx context.Context, p *Profile) context.Context {
+	return context.WithValue(ctx, profileKey{}, p)
+}
+
+func profileFrom(ctx context.Context) *Profile {
+	p, _ := ctx.Value(profileKey{}).(*Profile)
+	return p
+}
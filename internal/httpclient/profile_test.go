@@ -0,0 +1,78 @@
+package httpclient
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+var testProfile = &Profile{
+	Headers:     map[string]string{"X-Api-Key": "k-secret", "X-Auth-Token": "t-secret"},
+	Cookies:     map[string]string{"session": "c-secret"},
+	BearerToken: "b-secret",
+}
+
+func TestProfileRedacted(t *testing.T) {
+	r := testProfile.Redacted()
+	for name, value := range r.Headers {
+		if value != redacted {
+			t.Errorf("header %s = %q", name, value)
+		}
+	}
+	if len(r.Headers) != 2 || r.Cookies["session"] != redacted || r.BearerToken != redacted {
+		t.Errorf("redacted = %+v", r)
+	}
+	if testProfile.Headers["X-Api-Key"] != "k-secret" {
+		t.Error("Redacted modified the profile")
+	}
+
+	basic := (&Profile{BasicAuth: &BasicAuth{Username: "alice", Password: "p-secret"}}).Redacted()
+	if basic.BasicAuth.Username != "alice" || basic.BasicAuth.Password != redacted {
+		t.Errorf("basic auth = %+v", basic.BasicAuth)
+	}
+}
+
+func TestProfileApply(t *testing.T) {
+	tests := []struct {
+		redact bool
+		want   []string
+	}{
+		{false, []string{"X-Api-Key: k-secret", "X-Auth-Token: t-secret", "theme=dark; session=c-secret", "Bearer b-secret"}},
+		{true, []string{"X-Api-Key: " + redacted, "X-Auth-Token: " + redacted, "theme=dark; session=" + redacted, "Bearer " + redacted}},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest(http.MethodGet, "https://example.com/", nil)
+		req.Header.Set("Cookie", "theme=dark")
+		testProfile.apply(req, tt.redact)
+
+		got := []string{
+			"X-Api-Key: " + req.Header.Get("X-Api-Key"),
+			"X-Auth-Token: " + req.Header.Get("X-Auth-Token"),
+			req.Header.Get("Cookie"),
+			req.Header.Get("Authorization"),
+		}
+		if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+			t.Errorf("redact=%v:\n got %q\nwant %q", tt.redact, got, tt.want)
+		}
+
+		if tt.redact {
+			var dump strings.Builder
+			req.Header.Write(&dump)
+			if strings.Contains(dump.String(), "secret") {
+				t.Errorf("redacted request leaks a secret:\n%s", dump.String())
+			}
+		}
+	}
+}
+
+// Values the scanner injects are never overwritten by the profile
+func TestProfileApplyKeepsInjected(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "https://example.com/", nil)
+	req.Header.Set("X-Api-Key", "<payload>")
+	req.Header.Set("Cookie", "session=<payload>")
+	testProfile.apply(req, true)
+
+	if req.Header.Get("X-Api-Key") != "<payload>" || req.Header.Get("Cookie") != "session=<payload>" {
+		t.Errorf("injected values replaced: %v", req.Header)
+	}
+}
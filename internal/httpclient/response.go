@@ -0,0 +1,84 @@
+package httpclient
+
+import (
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"time"
+)
+
+// maxBodySize caps how much of a response body is read
+const maxBodySize = 1 << 20
+
+// Response is one completed exchange with everything scanners and proofs
+// need. The body is fully read and the connection released.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	Truncated  bool   // body was longer than maxBodySize
+	URL        string // final URL of the request that produced the response
+
+	Elapsed  time.Duration // request sent to body read, last attempt only
+	TTFB     time.Duration // request sent to first response byte
+	Attempts int
+
+	TLS *TLSInfo // nil for plain HTTP
+
+	RawRequest  []byte // request as sent on the wire
+	RawResponse []byte // status line, headers and (possibly truncated) body
+}
+
+// TLSInfo summarises the negotiated connection
+type TLSInfo struct {
+	Version            string    `json:"version"`
+	CipherSuite        string    `json:"cipher_suite"`
+	ServerName         string    `json:"server_name"`
+	NegotiatedProtocol string    `json:"negotiated_protocol,omitempty"`
+	Subject            string    `json:"subject,omitempty"`
+	Issuer             string    `json:"issuer,omitempty"`
+	DNSNames           []string  `json:"dns_names,omitempty"`
+	NotAfter           time.Time `json:"not_after,omitempty"`
+}
+
+func newTLSInfo(cs *tls.ConnectionState) *TLSInfo {
+	if cs == nil {
+		return nil
+	}
+
+	info := &TLSInfo{
+		Version:            tls.VersionName(cs.Version),
+		CipherSuite:        tls.CipherSuiteName(cs.CipherSuite),
+		ServerName:         cs.ServerName,
+		NegotiatedProtocol: cs.NegotiatedProtocol,
+	}
+	if len(cs.PeerCertificates) > 0 {
+		leaf := cs.PeerCertificates[0]
+		info.Subject = leaf.Subject.String()
+		info.Issuer = leaf.Issuer.String()
+		info.DNSNames = leaf.DNSNames
+		info.NotAfter = leaf.NotAfter
+	}
+	return info
+}
+
+// readBody reads at most maxBodySize bytes and closes the body
+func readBody(resp *http.Response) ([]byte, bool) {
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxBodySize+1))
+	if len(body) > maxBodySize {
+		return body[:maxBodySize], true
+	}
+	return body, false
+}
+
+// dumpResponse renders the response head followed by the body actually read
+func dumpResponse(resp *http.Response, body []byte) []byte {
+	head, err := httputil.DumpResponse(resp, false)
+	if err != nil {
+		return nil
+	}
+	return append(head, body...)
+}
@@ -0,0 +1,85 @@
+package httpclient
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"time"
+)
+
+// RetryPolicy decides how DoRequest repeats a failed request. 429 responses
+// and transport errors are always retryable.
+type RetryPolicy struct {
+	MaxRetries      int // after the first attempt
+	InitialInterval time.Duration
+	MaxElapsed      time.Duration
+
+	// ServerErrorsAsSignal returns 5xx responses to the caller instead of
+	// retrying them, for scanners that detect through server errors
+	ServerErrorsAsSignal bool
+}
+
+func defaultRetryPolicy(maxRetries int) RetryPolicy {
+	if maxRetries < 0 {
+		maxRetries = 0
+	}
+	return RetryPolicy{
+		MaxRetries:      maxRetries,
+		InitialInterval: 500 * time.Millisecond,
+		MaxElapsed:      10 * time.Second,
+	}
+}
+
+// RequestOption adjusts the retry policy of one DoRequest call
+type RequestOption func(*RetryPolicy)
+
+// WithMaxRetries overrides the configured number of retries
+func WithMaxRetries(n int) RequestOption {
+	return func(p *RetryPolicy) {
+		if n < 0 {
+			n = 0
+		}
+		p.MaxRetries = n
+	}
+}
+
+// WithoutRetry sends the request exactly once
+func WithoutRetry() RequestOption {
+	return WithMaxRetries(0)
+}
+
+// ServerErrorsAsSignal makes DoRequest return 5xx responses instead of
+// retrying them
+func ServerErrorsAsSignal() RequestOption {
+	return func(p *RetryPolicy) {
+		p.ServerErrorsAsSignal = true
+	}
+}
+
+// retryable reports whether a response with status should be retried
+func (p RetryPolicy) retryable(status int) bool {
+	if status == http.StatusTooManyRequests {
+		return true
+	}
+	return status >= 500 && !p.ServerErrorsAsSignal
+}
+
+// bufferBody makes req replayable: every attempt gets a fresh body from
+// GetBody, which is derived from a buffered copy when the caller did not
+// provide one
+func bufferBody(req *http.Request) error {
+	if req.Body == nil || req.Body == http.NoBody || req.GetBody != nil {
+		return nil
+	}
+
+	b, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return err
+	}
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(b)), nil
+	}
+	req.Body, _ = req.GetBody()
+	return nil
+}
@@ -3,65 +3,181 @@ package httpclient
 import (
 	"context"
 	"errors"
-	"io"
+	"fmt"
 	"net/http"
+	"net/http/httptrace"
+	"net/http/httputil"
 	"time"
 
 	"github.com/cenkalti/backoff/v4"
-	"golang.org/x/time/rate"
+	"github.com/kokuroshesh/bugvay/internal/config"
+	"github.com/kokuroshesh/bugvay/internal/ratelimit"
+	"github.com/kokuroshesh/bugvay/internal/scope"
 )
 
 type Scanner struct {
-	client  *http.Client
-	limiter *rate.Limiter
+	client    *http.Client
+	limiter   *ratelimit.Limiter
+	recorder  ProbeRecorder
+	retry     RetryPolicy
+	userAgent string
 }
 
-func NewScanner(rps int, timeout time.Duration) *Scanner {
+// NewScanner builds the scanning client. Requests are spaced per target
+// host by limiter, which may be shared across worker processes.
+func NewScanner(limiter *ratelimit.Limiter, cfg config.ScannerConfig) *Scanner {
 	return &Scanner{
 		client: &http.Client{
-			Timeout: timeout,
+			Transport: newTransport(),
+			Timeout:   time.Duration(cfg.Timeout) * time.Second,
 			CheckRedirect: func(req *http.Request, via []*http.Request) error {
 				return http.ErrUseLastResponse // Don't follow redirects
 			},
 		},
-		limiter: rate.NewLimiter(rate.Limit(rps), rps),
+		limiter:   limiter,
+		retry:     defaultRetryPolicy(cfg.MaxRetries),
+		userAgent: cfg.UserAgent,
 	}
 }
 
-func (s *Scanner) DoRequest(ctx context.Context, req *http.Request) (status int, body []byte, err error) {
-	// Rate limiting
-	if err := s.limiter.Wait(ctx); err != nil {
-		return 0, nil, err
+// DoRequest sends req, retrying transport errors, 429 and (unless
+// ServerErrorsAsSignal is given) 5xx responses with backoff. The body is
+// replayed on every attempt.
+func (s *Scanner) DoRequest(ctx context.Context, req *http.Request, opts ...RequestOption) (*Response, error) {
+	// Scope is enforced here so no scanner can reach an out-of-scope target
+	if sc, ok := scope.FromContext(ctx); ok {
+		if err := sc.Check(ctx, req.URL); err != nil {
+			return nil, err
+		}
+		// The dialer checks the connected address against it too
+		req = req.WithContext(scope.WithContext(req.Context(), sc))
+	}
+
+	policy := s.retry
+	for _, opt := range opts {
+		opt(&policy)
+	}
+
+	if err := bufferBody(req); err != nil {
+		return nil, fmt.Errorf("read request body: %w", err)
+	}
+	host := req.URL.Hostname()
+
+	// What gets recorded and stored as proof carries no credentials
+	profile := profileFrom(ctx)
+	shown, err := s.prepare(req.Context(), req, profile, true)
+	if err != nil {
+		return nil, err
 	}
+	rawRequest, _ := httputil.DumpRequestOut(shown, true)
 
-	// Retry policy with exponential backoff
 	bo := backoff.NewExponentialBackOff()
-	bo.InitialInterval = 500 * time.Millisecond
-	bo.MaxElapsedTime = 10 * time.Second
+	bo.InitialInterval = policy.InitialInterval
+	bo.MaxElapsedTime = policy.MaxElapsed
 
-	var resp *http.Response
+	var resp *Response
+	var latency time.Duration // of the last attempt, excluding rate limit waits
+	attempts := 0
 	op := func() error {
-		r, err := s.client.Do(req)
+		// Rate limiting, per attempt so retries respect backoff from the host
+		if err := s.limiter.Wait(ctx, host); err != nil {
+			return backoff.Permanent(err)
+		}
+		attempts++
+
+		var start, firstByte time.Time
+		trace := &httptrace.ClientTrace{
+			GotFirstResponseByte: func() { firstByte = time.Now() },
+		}
+		attempt, err := s.prepare(httptrace.WithClientTrace(req.Context(), trace), req, profile, false)
+		if err != nil {
+			return backoff.Permanent(err)
+		}
+
+		start = time.Now()
+		r, err := s.client.Do(attempt)
 		if err != nil {
+			latency = time.Since(start)
+			s.limiter.Observe(ctx, host, nil, latency)
+			if errors.Is(err, scope.ErrOutOfScope) {
+				return backoff.Permanent(err)
+			}
 			return err
 		}
-		resp = r
+		body, truncated := readBody(r)
+		latency = time.Since(start)
+		s.limiter.Observe(ctx, host, r, latency)
+
+		if policy.retryable(r.StatusCode) {
+			return fmt.Errorf("server returned %d", r.StatusCode)
+		}
 
-		// Treat 5xx as retryable
-		if resp.StatusCode >= 500 {
-			io.Copy(io.Discard, resp.Body)
-			resp.Body.Close()
-			return errors.New("server error")
+		resp = &Response{
+			StatusCode:  r.StatusCode,
+			Header:      r.Header,
+			Body:        body,
+			Truncated:   truncated,
+			URL:         r.Request.URL.String(),
+			Elapsed:     latency,
+			TLS:         newTLSInfo(r.TLS),
+			RawRequest:  rawRequest,
+			RawResponse: dumpResponse(r, body),
+		}
+		if !firstByte.IsZero() {
+			resp.TTFB = firstByte.Sub(start)
 		}
 		return nil
 	}
 
-	err = backoff.Retry(op, bo)
-	if err != nil {
-		return 0, nil, err
+	var b backoff.BackOff = backoff.WithMaxRetries(bo, uint64(policy.MaxRetries))
+	if err := backoff.Retry(op, backoff.WithContext(b, ctx)); err != nil {
+		s.record(ctx, shown, nil, latency, err)
+		return nil, err
+	}
+
+	resp.Attempts = attempts
+	s.record(ctx, shown, resp, latency, nil)
+	return resp, nil
+}
+
+// prepare copies req for one attempt with a fresh body, the scanner's
+// User-Agent and the program's request profile
+func (s *Scanner) prepare(ctx context.Context, req *http.Request, profile *Profile, redact bool) (*http.Request, error) {
+	out := req.Clone(ctx)
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		out.Body = body
+	}
+
+	profile.apply(out, redact)
+	if out.Header.Get("User-Agent") == "" && s.userAgent != "" {
+		out.Header.Set("User-Agent", s.userAgent)
+	}
+	return out, nil
+}
+
+func (s *Scanner) record(ctx context.Context, req *http.Request, resp *Response, latency time.Duration, err error) {
+	if s.recorder == nil {
+		return
+	}
+
+	p := &Probe{
+		Method:         req.Method,
+		URL:            req.URL.String(),
+		Payload:        payloadFrom(ctx),
+		RequestHeaders: req.Header,
+		Latency:        latency,
+		Err:            err,
+	}
+	if resp != nil {
+		p.Status = resp.StatusCode
+		p.ContentLength = len(resp.Body)
+		p.Body = resp.Body
+		p.ResponseHeaders = resp.Header
 	}
 
-	defer resp.Body.Close()
-	b, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20)) // Limit 1MB
-	return resp.StatusCode, b, nil
+	s.recorder.RecordProbe(ctx, p)
 }
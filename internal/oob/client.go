@@ -0,0 +1,95 @@
+package oob
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/kokuroshesh/bugvay/internal/config"
+)
+
+// defaultWait applies when OOB_WAIT is unset. Targets often fetch URLs from
+// a background job, so callbacks can lag the response.
+const defaultWait = 10 * time.Second
+
+// pollInterval is how often Wait checks the store for new interactions
+const pollInterval = 500 * time.Millisecond
+
+// Client issues tokens and builds callback locations for scanners
+type Client struct {
+	publicHost string
+	domain     string
+	wait       time.Duration
+	store      Store
+}
+
+// NewClient builds a client for the interaction server reachable at
+// cfg.PublicHost and, when set, cfg.Domain
+func NewClient(cfg config.OOBConfig, store Store) *Client {
+	wait := time.Duration(cfg.Wait) * time.Second
+	if wait <= 0 {
+		wait = defaultWait
+	}
+	return &Client{
+		publicHost: cfg.PublicHost,
+		domain:     strings.TrimSuffix(cfg.Domain, "."),
+		wait:       wait,
+		store:      store,
+	}
+}
+
+// Register records what token is about to be injected with. Callbacks
+// for unregistered tokens are dropped.
+func (c *Client) Register(ctx context.Context, token string, tag Tag) error {
+	return c.store.RegisterToken(ctx, token, tag)
+}
+
+// URL is an HTTP callback URL carrying token
+func (c *Client) URL(token string) string {
+	return "http://" + c.publicHost + "/" + token
+}
+
+// Host is a DNS name carrying token, or "" when no domain is delegated to
+// the interaction server
+func (c *Client) Host(token string) string {
+	if c.domain == "" {
+		return ""
+	}
+	return token + "." + c.domain
+}
+
+// Email is a mail address carrying token, or "" without a domain
+func (c *Client) Email(token string) string {
+	if c.domain == "" {
+		return ""
+	}
+	return token + "@" + c.domain
+}
+
+// Wait blocks until any of tokens has received an interaction or the
+// configured wait has elapsed, and returns the interactions seen so far
+func (c *Client) Wait(ctx context.Context, tokens []string) []Interaction {
+	timer := time.NewTimer(c.wait)
+	defer timer.Stop()
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		hits, err := c.store.Interactions(ctx, tokens)
+		if err != nil {
+			log.Printf("[oob] Failed to load interactions: %v", err)
+		}
+		if len(hits) > 0 {
+			return hits
+		}
+
+		select {
+		case <-ticker.C:
+		case <-timer.C:
+			return nil
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
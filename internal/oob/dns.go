@@ -0,0 +1,116 @@
+package oob
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// A minimal authoritative responder: enough to see which names a target
+// resolves and to point A queries at the HTTP listener.
+
+const (
+	dnsHeaderLen = 12
+	dnsTypeA     = 1
+	dnsClassIN   = 1
+)
+
+func (s *Server) serveDNS(conn net.PacketConn) {
+	buf := make([]byte, 512)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return // closed
+		}
+
+		name, qtype, end, ok := parseQuestion(buf[:n])
+		if !ok {
+			continue
+		}
+		s.record(name, Interaction{
+			Protocol:   "dns",
+			RemoteAddr: addr.String(),
+			Request:    fmt.Sprintf("%s %s", name, dnsTypeName(qtype)),
+			Time:       time.Now(),
+		})
+
+		conn.WriteTo(s.dnsAnswer(buf[:end], qtype), addr)
+	}
+}
+
+// parseQuestion returns the first question's name and type and where the
+// question section ends
+func parseQuestion(msg []byte) (name string, qtype uint16, end int, ok bool) {
+	if len(msg) < dnsHeaderLen || binary.BigEndian.Uint16(msg[4:6]) == 0 {
+		return "", 0, 0, false
+	}
+
+	var labels []string
+	i := dnsHeaderLen
+	for {
+		if i >= len(msg) {
+			return "", 0, 0, false
+		}
+		l := int(msg[i])
+		i++
+		if l == 0 {
+			break
+		}
+		if l > 63 || i+l > len(msg) {
+			return "", 0, 0, false // compression is not used in questions
+		}
+		labels = append(labels, string(msg[i:i+l]))
+		i += l
+	}
+	if i+4 > len(msg) {
+		return "", 0, 0, false
+	}
+	qtype = binary.BigEndian.Uint16(msg[i : i+2])
+	return strings.ToLower(strings.Join(labels, ".")), qtype, i + 4, true
+}
+
+// dnsAnswer echoes the query's header and question. A queries under the
+// delegated domain are answered with the public IP so HTTP follows.
+func (s *Server) dnsAnswer(query []byte, qtype uint16) []byte {
+	resp := make([]byte, len(query), len(query)+16)
+	copy(resp, query)
+
+	flags := binary.BigEndian.Uint16(query[2:4])
+	flags = 0x8000 | 0x0400 | flags&0x0100 // QR, AA, copy RD
+	binary.BigEndian.PutUint16(resp[2:4], flags)
+	binary.BigEndian.PutUint16(resp[4:6], 1) // QDCOUNT
+	binary.BigEndian.PutUint16(resp[8:10], 0)
+	binary.BigEndian.PutUint16(resp[10:12], 0)
+
+	ip := net.ParseIP(s.cfg.PublicIP).To4()
+	if qtype != dnsTypeA || ip == nil {
+		binary.BigEndian.PutUint16(resp[6:8], 0)
+		return resp
+	}
+
+	binary.BigEndian.PutUint16(resp[6:8], 1) // ANCOUNT
+	resp = append(resp, 0xc0, dnsHeaderLen)  // pointer to the question name
+	resp = binary.BigEndian.AppendUint16(resp, dnsTypeA)
+	resp = binary.BigEndian.AppendUint16(resp, dnsClassIN)
+	resp = binary.BigEndian.AppendUint32(resp, 0) // TTL: every lookup reaches us
+	resp = binary.BigEndian.AppendUint16(resp, 4)
+	return append(resp, ip...)
+}
+
+func dnsTypeName(qtype uint16) string {
+	switch qtype {
+	case 1:
+		return "A"
+	case 5:
+		return "CNAME"
+	case 15:
+		return "MX"
+	case 16:
+		return "TXT"
+	case 28:
+		return "AAAA"
+	}
+	return fmt.Sprintf("TYPE%d", qtype)
+}
@@ -0,0 +1,43 @@
+package oob
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"strings"
+	"time"
+)
+
+// maxRecordedRequest caps how much of a callback request is kept
+const maxRecordedRequest = 8 << 10
+
+func (s *Server) serveHTTP(ln net.Listener) {
+	srv := &http.Server{
+		Handler:           http.HandlerFunc(s.handleHTTP),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	srv.Serve(ln)
+}
+
+// handleHTTP accepts any method and path. The token may be in the path,
+// the Host header or the query.
+func (s *Server) handleHTTP(w http.ResponseWriter, r *http.Request) {
+	r.Body = io.NopCloser(io.LimitReader(r.Body, maxRecordedRequest))
+	raw, _ := httputil.DumpRequest(r, true)
+	if len(raw) > maxRecordedRequest {
+		raw = raw[:maxRecordedRequest]
+	}
+
+	s.record(r.Host+" "+r.URL.RequestURI(), Interaction{
+		Protocol:   "http",
+		RemoteAddr: r.RemoteAddr,
+		Request:    string(raw),
+		Time:       time.Now(),
+	})
+
+	w.Header().Set("Content-Type", "text/plain")
+	if token := tokenRe.FindString(strings.ToLower(r.Host + r.URL.Path)); token != "" {
+		io.WriteString(w, Marker(token))
+	}
+}
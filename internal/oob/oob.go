@@ -0,0 +1,81 @@
+// Package oob is the out-of-band interaction service. Scanners embed a
+// unique token in payloads that point at it; when a target fetches the
+// URL, resolves the name or sends mail to it, the callback is matched back
+// to the endpoint and parameter the token was issued for.
+//
+// Server runs the HTTP, DNS and SMTP listeners, either in cmd/oob or
+// embedded in a worker. Client is what scanners use. Both share a Store,
+// so callbacks that arrive after a scan finished are still attributed.
+package oob
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"regexp"
+	"time"
+)
+
+// ErrUnavailable is returned by scanners that need an interaction server
+// when none is configured
+var ErrUnavailable = errors.New("out-of-band interaction server not configured")
+
+// tokenRe matches tokens inside hostnames, paths, DNS names and mail
+// addresses. Tokens are lowercase because DNS names are case-insensitive.
+var tokenRe = regexp.MustCompile(`bv[0-9a-f]{16}`)
+
+// Tag records where a token was injected
+type Tag struct {
+	EndpointID int
+	Scanner    string
+	Param      string
+	Location   string
+	Payload    string
+}
+
+// Interaction is one callback received from a target
+type Interaction struct {
+	Token      string
+	Protocol   string // "http", "dns" or "smtp"
+	RemoteAddr string
+	Request    string // raw HTTP request, DNS question or SMTP transcript
+	Time       time.Time
+}
+
+// Store persists issued tokens and the interactions they receive
+type Store interface {
+	RegisterToken(ctx context.Context, token string, tag Tag) error
+	// RecordInteraction reports false when the token was never issued
+	RecordInteraction(ctx context.Context, in Interaction) (bool, error)
+	Interactions(ctx context.Context, tokens []string) ([]Interaction, error)
+}
+
+// NewToken returns a fresh correlation token
+func NewToken() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return "bv" + hex.EncodeToString(b)
+}
+
+// Marker is what the HTTP listener answers for token. Finding it in a
+// target's response means the fetched content was returned to the client;
+// unlike the token it never appears in the payload itself.
+func Marker(token string) string {
+	sum := sha256.Sum256([]byte("bugvay-oob:" + token))
+	return "bvoob" + hex.EncodeToString(sum[:8])
+}
+
+type contextKey struct{}
+
+// WithContext makes c available to scanners run with ctx
+func WithContext(ctx context.Context, c *Client) context.Context {
+	return context.WithValue(ctx, contextKey{}, c)
+}
+
+// FromContext returns the interaction client attached to ctx, if any
+func FromContext(ctx context.Context) (*Client, bool) {
+	c, ok := ctx.Value(contextKey{}).(*Client)
+	return c, ok && c != nil
+}
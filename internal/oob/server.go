@@ -0,0 +1,115 @@
+package oob
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kokuroshesh/bugvay/internal/config"
+)
+
+// recordTimeout bounds how long a listener waits on the store
+const recordTimeout = 5 * time.Second
+
+// Server runs the HTTP, DNS and SMTP listeners and stores every
+// interaction that carries a known token
+type Server struct {
+	cfg   config.OOBConfig
+	store Store
+
+	httpLn  net.Listener
+	dnsConn net.PacketConn
+	smtpLn  net.Listener
+	wg      sync.WaitGroup
+}
+
+func NewServer(cfg config.OOBConfig, store Store) *Server {
+	return &Server{cfg: cfg, store: store}
+}
+
+// Start opens the configured listeners. HTTP is required, DNS and SMTP
+// are optional.
+func (s *Server) Start() error {
+	ln, err := net.Listen("tcp", s.cfg.HTTPAddr)
+	if err != nil {
+		return fmt.Errorf("oob http listener: %w", err)
+	}
+	s.httpLn = ln
+	s.serve(func() { s.serveHTTP(ln) })
+
+	if s.cfg.DNSAddr != "" {
+		conn, err := net.ListenPacket("udp", s.cfg.DNSAddr)
+		if err != nil {
+			s.Close()
+			return fmt.Errorf("oob dns listener: %w", err)
+		}
+		s.dnsConn = conn
+		s.serve(func() { s.serveDNS(conn) })
+	}
+
+	if s.cfg.SMTPAddr != "" {
+		ln, err := net.Listen("tcp", s.cfg.SMTPAddr)
+		if err != nil {
+			s.Close()
+			return fmt.Errorf("oob smtp listener: %w", err)
+		}
+		s.smtpLn = ln
+		s.serve(func() { s.serveSMTP(ln) })
+	}
+	return nil
+}
+
+func (s *Server) serve(fn func()) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		fn()
+	}()
+}
+
+// HTTPAddr is the address the HTTP listener is bound to
+func (s *Server) HTTPAddr() string {
+	return s.httpLn.Addr().String()
+}
+
+// Close stops the listeners and waits for them to exit
+func (s *Server) Close() {
+	if s.httpLn != nil {
+		s.httpLn.Close()
+	}
+	if s.dnsConn != nil {
+		s.dnsConn.Close()
+	}
+	if s.smtpLn != nil {
+		s.smtpLn.Close()
+	}
+	s.wg.Wait()
+}
+
+// record stores in once for every token found in text
+func (s *Server) record(text string, in Interaction) {
+	ctx, cancel := context.WithTimeout(context.Background(), recordTimeout)
+	defer cancel()
+
+	seen := make(map[string]bool)
+	for _, token := range tokenRe.FindAllString(strings.ToLower(text), -1) {
+		if seen[token] {
+			continue
+		}
+		seen[token] = true
+
+		in.Token = token
+		known, err := s.store.RecordInteraction(ctx, in)
+		if err != nil {
+			log.Printf("[oob] Failed to record %s interaction for %s: %v", in.Protocol, token, err)
+			continue
+		}
+		if known {
+			log.Printf("[oob] %s interaction from %s for %s", in.Protocol, in.RemoteAddr, token)
+		}
+	}
+}
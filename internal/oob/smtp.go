@@ -0,0 +1,118 @@
+package oob
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// A minimal SMTP sink: it accepts any envelope and message so targets that
+// send mail to a token address (password resets, notifications, header
+// injection) produce an interaction. Nothing is relayed.
+
+const (
+	smtpTimeout    = 30 * time.Second // per line
+	smtpSession    = 5 * time.Minute  // per connection
+	smtpMaxLine    = 1024             // RFC 5321 text lines are 1000 bytes
+	smtpMaxConns   = 64
+	maxTranscript  = 8 << 10
+	smtpHostname   = "bugvay-oob"
+	smtpMaxCommand = 100
+)
+
+func (s *Server) serveSMTP(ln net.Listener) {
+	// The port is open to the internet; idle or slow clients must not pile up
+	slots := make(chan struct{}, smtpMaxConns)
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return // closed
+		}
+		select {
+		case slots <- struct{}{}:
+			go func() {
+				defer func() { <-slots }()
+				s.handleSMTP(conn)
+			}()
+		default:
+			conn.Close()
+		}
+	}
+}
+
+func (s *Server) handleSMTP(conn net.Conn) {
+	defer conn.Close()
+	end := time.Now().Add(smtpSession)
+	deadline := func() time.Time {
+		if d := time.Now().Add(smtpTimeout); d.Before(end) {
+			return d
+		}
+		return end
+	}
+
+	var transcript strings.Builder
+	defer func() {
+		s.record(transcript.String(), Interaction{
+			Protocol:   "smtp",
+			RemoteAddr: conn.RemoteAddr().String(),
+			Request:    transcript.String(),
+			Time:       time.Now(),
+		})
+	}()
+
+	r := bufio.NewReaderSize(conn, smtpMaxLine)
+	reply := func(format string, args ...interface{}) {
+		conn.SetWriteDeadline(deadline())
+		fmt.Fprintf(conn, format+"\r\n", args...)
+	}
+	readLine := func() (string, bool) {
+		conn.SetReadDeadline(deadline())
+		line, err := r.ReadSlice('\n')
+		if err == bufio.ErrBufferFull {
+			reply("500 Line too long")
+			return "", false
+		}
+		if err != nil {
+			return "", false
+		}
+		if transcript.Len()+len(line) <= maxTranscript {
+			transcript.Write(line)
+		}
+		return strings.TrimRight(string(line), "\r\n"), true
+	}
+
+	reply("220 %s ESMTP", smtpHostname)
+	for i := 0; i < smtpMaxCommand; i++ {
+		line, ok := readLine()
+		if !ok {
+			return
+		}
+
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch verb {
+		case "HELO", "EHLO":
+			reply("250 %s", smtpHostname)
+		case "MAIL", "RCPT", "RSET", "NOOP":
+			reply("250 OK")
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			for {
+				line, ok := readLine()
+				if !ok {
+					return
+				}
+				if line == "." {
+					break
+				}
+			}
+			reply("250 OK")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
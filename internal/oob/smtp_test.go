@@ -0,0 +1,105 @@
+package oob
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kokuroshesh/bugvay/internal/config"
+)
+
+type memStore struct {
+	mu   sync.Mutex
+	hits []Interaction
+}
+
+func (m *memStore) RegisterToken(ctx context.Context, token string, tag Tag) error { return nil }
+
+func (m *memStore) RecordInteraction(ctx context.Context, in Interaction) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.hits = append(m.hits, in)
+	return true, nil
+}
+
+func (m *memStore) Interactions(ctx context.Context, tokens []string) ([]Interaction, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Interaction(nil), m.hits...), nil
+}
+
+func startSMTP(t *testing.T) (*Server, *memStore) {
+	t.Helper()
+	store := &memStore{}
+	srv := NewServer(config.OOBConfig{HTTPAddr: "127.0.0.1:0", SMTPAddr: "127.0.0.1:0"}, store)
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(srv.Close)
+	return srv, store
+}
+
+func dialSMTP(t *testing.T, srv *Server) (net.Conn, *bufio.Reader) {
+	t.Helper()
+	conn, err := net.Dial("tcp", srv.smtpLn.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	r := bufio.NewReader(conn)
+	if line, _ := r.ReadString('\n'); !strings.HasPrefix(line, "220 ") {
+		t.Fatalf("greeting = %q", line)
+	}
+	return conn, r
+}
+
+func TestSMTPRecordsToken(t *testing.T) {
+	srv, store := startSMTP(t)
+	conn, r := dialSMTP(t, srv)
+
+	token := NewToken()
+	for _, cmd := range []string{"EHLO target", "MAIL FROM:<app@target>", "RCPT TO:<" + token + "@oob.test>", "DATA"} {
+		fmt.Fprintf(conn, "%s\r\n", cmd)
+		r.ReadString('\n')
+	}
+	fmt.Fprintf(conn, "Subject: reset\r\n\r\nhello\r\n.\r\nQUIT\r\n")
+	r.ReadString('\n')
+	if line, _ := r.ReadString('\n'); !strings.HasPrefix(line, "221 ") {
+		t.Fatalf("QUIT reply = %q", line)
+	}
+	conn.Close()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if hits, _ := store.Interactions(context.Background(), nil); len(hits) > 0 {
+			if hits[0].Token != token || hits[0].Protocol != "smtp" || !strings.Contains(hits[0].Request, "Subject: reset") {
+				t.Errorf("interaction = %+v", hits[0])
+			}
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("no interaction recorded")
+}
+
+func TestSMTPRejectsLongLine(t *testing.T) {
+	srv, _ := startSMTP(t)
+	conn, r := dialSMTP(t, srv)
+	defer conn.Close()
+
+	// No newline ever comes; the server must not keep buffering
+	go conn.Write([]byte("EHLO " + strings.Repeat("a", 64<<10)))
+
+	line, _ := r.ReadString('\n')
+	if !strings.HasPrefix(line, "500 ") {
+		t.Fatalf("reply = %q, want 500", line)
+	}
+	if _, err := r.ReadString('\n'); err == nil {
+		t.Error("connection still open after an overlong line")
+	}
+}
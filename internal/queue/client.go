@@ -8,57 +8,57 @@ import (
 
 	"github.com/hibiken/asynq"
 	"github.com/kokuroshesh/bugvay/internal/config"
-)
-
-const (
-	TypeScanXSS      = "scan:xss"
-	TypeScanSQLi     = "scan:sqli"
-	TypeScanLFI      = "scan:lfi"
-	TypeScanRedirect = "scan:redirect"
+	"github.com/kokuroshesh/bugvay/internal/scanners"
 )
 
 type Client struct {
 	*asynq.Client
+	inspector *asynq.Inspector
 }
 
 func NewClient(cfg *config.RedisConfig) *Client {
-	client := asynq.NewClient(asynq.RedisClientOpt{
+	opt := asynq.RedisClientOpt{
 		Addr:     cfg.Addr(),
 		Password: cfg.Password,
 		DB:       cfg.DB,
-	})
+	}
+
+	return &Client{
+		Client:    asynq.NewClient(opt),
+		inspector: asynq.NewInspector(opt),
+	}
+}
 
-	return &Client{Client: client}
+func (c *Client) Close() error {
+	c.inspector.Close()
+	return c.Client.Close()
 }
 
 type ScanPayload struct {
-	EndpointID int    `json:"endpoint_id"`
-	Scanner    string `json:"scanner"`
-	URL        string `json:"url"`
+	ScanID     string            `json:"scan_id,omitempty"`
+	EndpointID int               `json:"endpoint_id"`
+	Scanner    string            `json:"scanner"`
+	URL        string            `json:"url"`
+	Method     string            `json:"method,omitempty"` // defaults to GET
+	Headers    map[string]string `json:"headers,omitempty"`
+	Body       string            `json:"body,omitempty"`
 }
 
-func (c *Client) EnqueueScan(ctx context.Context, scanner string, endpointID int, payload []byte) (string, error) {
-	taskType := ""
-	switch scanner {
-	case "xss":
-		taskType = TypeScanXSS
-	case "sqli":
-		taskType = TypeScanSQLi
-	case "lfi":
-		taskType = TypeScanLFI
-	case "redirect":
-		taskType = TypeScanRedirect
-	default:
+func (c *Client) EnqueueScan(ctx context.Context, scanner string, endpointID int, payload []byte, opts ...asynq.Option) (string, error) {
+	reg, ok := scanners.Lookup(scanner)
+	if !ok {
 		return "", fmt.Errorf("unknown scanner: %s", scanner)
 	}
 
-	task := asynq.NewTask(taskType, payload,
+	task := asynq.NewTask(reg.TaskType, payload,
 		asynq.MaxRetry(3),
-		asynq.Timeout(5*time.Minute),
-		asynq.Queue("default"),
+		asynq.Timeout(reg.Timeout),
+		asynq.Queue(reg.Queue),
+		asynq.Retention(24*time.Hour), // keep the task result around for inspection
 	)
 
-	info, err := c.Enqueue(task, asynq.ProcessIn(1*time.Second))
+	opts = append([]asynq.Option{asynq.ProcessIn(1 * time.Second)}, opts...)
+	info, err := c.Enqueue(task, opts...)
 	if err != nil {
 		return "", fmt.Errorf("enqueue task: %w", err)
 	}
@@ -66,15 +66,6 @@ func (c *Client) EnqueueScan(ctx context.Context, scanner string, endpointID int
 	return info.ID, nil
 }
 
-func (c *Client) GetJobStatus(ctx context.Context, jobID string) (string, error) {
-	// TODO: Query Asynq inspector for job status
-	return "running", nil
-}
-
-func NewScanPayload(endpointID int, scanner, url string) ([]byte, error) {
-	return json.Marshal(ScanPayload{
-		EndpointID: endpointID,
-		Scanner:    scanner,
-		URL:        url,
-	})
+func NewScanPayload(p ScanPayload) ([]byte, error) {
+	return json.Marshal(p)
 }
@@ -0,0 +1,211 @@
+package queue
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hibiken/asynq"
+)
+
+// Job states understood by ListJobs
+const (
+	StatePending   = "pending"
+	StateActive    = "active"
+	StateScheduled = "scheduled"
+	StateRetry     = "retry"
+	StateArchived  = "archived"
+	StateCompleted = "completed"
+)
+
+var (
+	ErrJobNotFound     = errors.New("job not found")
+	ErrInvalidJobState = errors.New("invalid job state")
+)
+
+type JobInfo struct {
+	ID            string          `json:"id"`
+	Type          string          `json:"type"`
+	Queue         string          `json:"queue"`
+	State         string          `json:"state"`
+	Payload       json.RawMessage `json:"payload"`
+	Retried       int             `json:"retried"`
+	MaxRetry      int             `json:"max_retry"`
+	LastError     string          `json:"last_error,omitempty"`
+	LastFailedAt  *time.Time      `json:"last_failed_at,omitempty"`
+	NextProcessAt *time.Time      `json:"next_process_at,omitempty"`
+	CompletedAt   *time.Time      `json:"completed_at,omitempty"`
+	Result        json.RawMessage `json:"result,omitempty"`
+}
+
+// ListJobs lists tasks in the given state. An empty queue lists across all
+// queues, ordered by queue name, and pages through that merged list; page
+// starts at 1.
+func (c *Client) ListJobs(queue, state string, page, size int) ([]JobInfo, error) {
+	if page < 1 {
+		page = 1
+	}
+	if size < 1 {
+		size = 50
+	}
+
+	if queue != "" {
+		return c.listJobs(queue, state, asynq.Page(page), asynq.PageSize(size))
+	}
+
+	queues, err := c.inspector.Queues()
+	if err != nil {
+		return nil, fmt.Errorf("list queues: %w", err)
+	}
+	sort.Strings(queues)
+
+	// The requested page can only hold tasks from the first page*size of
+	// each queue
+	jobs := []JobInfo{}
+	for _, q := range queues {
+		tasks, err := c.listJobs(q, state, asynq.Page(1), asynq.PageSize(page*size))
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, tasks...)
+		if len(jobs) >= page*size {
+			break
+		}
+	}
+
+	start := (page - 1) * size
+	if start >= len(jobs) {
+		return []JobInfo{}, nil
+	}
+	end := start + size
+	if end > len(jobs) {
+		end = len(jobs)
+	}
+	return jobs[start:end], nil
+}
+
+// listJobs lists one page of tasks in state from a single queue
+func (c *Client) listJobs(queue, state string, opts ...asynq.ListOption) ([]JobInfo, error) {
+	var (
+		tasks []*asynq.TaskInfo
+		err   error
+	)
+	switch state {
+	case StatePending:
+		tasks, err = c.inspector.ListPendingTasks(queue, opts...)
+	case StateActive:
+		tasks, err = c.inspector.ListActiveTasks(queue, opts...)
+	case StateScheduled:
+		tasks, err = c.inspector.ListScheduledTasks(queue, opts...)
+	case StateRetry:
+		tasks, err = c.inspector.ListRetryTasks(queue, opts...)
+	case StateArchived:
+		tasks, err = c.inspector.ListArchivedTasks(queue, opts...)
+	case StateCompleted:
+		tasks, err = c.inspector.ListCompletedTasks(queue, opts...)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrInvalidJobState, state)
+	}
+	if errors.Is(err, asynq.ErrQueueNotFound) {
+		return []JobInfo{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("list %s tasks in %s: %w", state, queue, err)
+	}
+
+	jobs := make([]JobInfo, 0, len(tasks))
+	for _, t := range tasks {
+		jobs = append(jobs, newJobInfo(t))
+	}
+	return jobs, nil
+}
+
+// GetJob looks a task up by ID in every queue
+func (c *Client) GetJob(jobID string) (*JobInfo, error) {
+	queues, err := c.inspector.Queues()
+	if err != nil {
+		return nil, fmt.Errorf("list queues: %w", err)
+	}
+
+	for _, q := range queues {
+		t, err := c.inspector.GetTaskInfo(q, jobID)
+		if errors.Is(err, asynq.ErrTaskNotFound) || errors.Is(err, asynq.ErrQueueNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("get task info: %w", err)
+		}
+
+		job := newJobInfo(t)
+		return &job, nil
+	}
+
+	return nil, ErrJobNotFound
+}
+
+func newJobInfo(t *asynq.TaskInfo) JobInfo {
+	job := JobInfo{
+		ID:        t.ID,
+		Type:      t.Type,
+		Queue:     t.Queue,
+		State:     t.State.String(),
+		Payload:   rawJSON(t.Payload),
+		Retried:   t.Retried,
+		MaxRetry:  t.MaxRetry,
+		LastError: t.LastErr,
+		Result:    rawJSON(t.Result),
+	}
+
+	if !t.LastFailedAt.IsZero() {
+		job.LastFailedAt = &t.LastFailedAt
+	}
+	if !t.NextProcessAt.IsZero() {
+		job.NextProcessAt = &t.NextProcessAt
+	}
+	if !t.CompletedAt.IsZero() {
+		job.CompletedAt = &t.CompletedAt
+	}
+
+	return job
+}
+
+// rawJSON passes JSON through untouched and quotes anything else
+func rawJSON(b []byte) json.RawMessage {
+	if len(b) == 0 {
+		return nil
+	}
+	if json.Valid(b) {
+		return b
+	}
+	quoted, _ := json.Marshal(string(b))
+	return quoted
+}
+
+// DeleteJob removes a task that is not currently being processed
+func (c *Client) DeleteJob(queue, jobID string) error {
+	return ignoreNotFound(c.inspector.DeleteTask(queue, jobID))
+}
+
+// ArchiveJob parks a pending, scheduled or retry task until RunJob is called
+func (c *Client) ArchiveJob(queue, jobID string) error {
+	return ignoreNotFound(c.inspector.ArchiveTask(queue, jobID))
+}
+
+// RunJob moves an archived, scheduled or retry task back to pending
+func (c *Client) RunJob(queue, jobID string) error {
+	return ignoreNotFound(c.inspector.RunTask(queue, jobID))
+}
+
+// CancelJob signals the worker processing the task to cancel its context
+func (c *Client) CancelJob(jobID string) error {
+	return c.inspector.CancelProcessing(jobID)
+}
+
+func ignoreNotFound(err error) error {
+	if errors.Is(err, asynq.ErrTaskNotFound) || errors.Is(err, asynq.ErrQueueNotFound) {
+		return nil
+	}
+	return err
+}
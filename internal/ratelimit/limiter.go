@@ -0,0 +1,235 @@
+// Package ratelimit spaces out requests per target host. With a Redis
+// client the budget is shared by every worker process; without one each
+// process keeps its own limiters.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/time/rate"
+)
+
+const (
+	minRate   = 0.5 // requests per second a backed-off host never drops below
+	stateTTL  = time.Hour
+	keyPrefix = "bugvay:ratelimit:"
+)
+
+// Limiter hands out request slots per host and adapts the rate to how the
+// host responds
+type Limiter struct {
+	rdb        *redis.Client
+	defaultRPS float64
+
+	mu    sync.Mutex
+	hosts map[string]*hostState
+}
+
+// hostState is the process-local view of one host. The authoritative rate
+// lives in Redis when one is configured.
+type hostState struct {
+	limiter      *rate.Limiter // used without Redis
+	blockedUntil time.Time     // Retry-After, used without Redis
+
+	fastLatency float64 // EWMA, ms
+	slowLatency float64 // EWMA, ms
+	samples     int
+	successes   int
+	lastAdjust  time.Time
+}
+
+func New(rdb *redis.Client, defaultRPS int) *Limiter {
+	if defaultRPS <= 0 {
+		defaultRPS = 10
+	}
+	return &Limiter{
+		rdb:        rdb,
+		defaultRPS: float64(defaultRPS),
+		hosts:      map[string]*hostState{},
+	}
+}
+
+type contextKey struct{}
+
+// WithRate overrides the per-host rate for requests made with ctx, e.g. a
+// program that only allows 5 req/s
+func WithRate(ctx context.Context, rps int) context.Context {
+	if rps <= 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, contextKey{}, float64(rps))
+}
+
+func (l *Limiter) maxRate(ctx context.Context) float64 {
+	if rps, ok := ctx.Value(contextKey{}).(float64); ok {
+		return rps
+	}
+	return l.defaultRPS
+}
+
+// Wait blocks until a request to host may be sent
+func (l *Limiter) Wait(ctx context.Context, host string) error {
+	max := l.maxRate(ctx)
+
+	if l.rdb == nil {
+		return l.waitLocal(ctx, host, max)
+	}
+
+	ms, err := reserveScript.Run(ctx, l.rdb,
+		[]string{keyPrefix + host + ":tat", keyPrefix + host + ":rate"},
+		max, minRate, stateTTL.Milliseconds(),
+	).Int64()
+	if err != nil {
+		// Redis trouble must not stop scanning, but must not unthrottle it either
+		return l.waitLocal(ctx, host, max)
+	}
+
+	return sleep(ctx, time.Duration(ms)*time.Millisecond)
+}
+
+func (l *Limiter) waitLocal(ctx context.Context, host string, max float64) error {
+	l.mu.Lock()
+	st := l.state(host, max)
+	if st.limiter.Limit() > rate.Limit(max) {
+		st.limiter.SetLimit(rate.Limit(max))
+	}
+	blocked := time.Until(st.blockedUntil)
+	l.mu.Unlock()
+
+	if err := sleep(ctx, blocked); err != nil {
+		return err
+	}
+	return st.limiter.Wait(ctx)
+}
+
+// Observe feeds a response back into the host's rate: 429/503 halve it,
+// Retry-After pauses the host, rising latency slows it down and a long run
+// of healthy responses speeds it back up to the allowed maximum
+func (l *Limiter) Observe(ctx context.Context, host string, resp *http.Response, latency time.Duration) {
+	max := l.maxRate(ctx)
+
+	l.mu.Lock()
+	st := l.state(host, max)
+
+	factor := 1.0
+	var pause time.Duration
+
+	switch {
+	case resp == nil:
+		// Transport error: no signal about server load
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable:
+		factor = 0.5
+		st.successes = 0
+		pause = retryAfter(resp.Header)
+	default:
+		ms := float64(latency.Milliseconds())
+		if st.samples == 0 {
+			st.fastLatency, st.slowLatency = ms, ms
+		}
+		st.fastLatency = 0.3*ms + 0.7*st.fastLatency
+		st.slowLatency = 0.02*ms + 0.98*st.slowLatency
+		st.samples++
+		st.successes++
+
+		rising := st.samples >= 10 && st.fastLatency > 2*st.slowLatency
+		if rising && time.Since(st.lastAdjust) > 5*time.Second {
+			factor = 0.8
+			st.successes = 0
+		} else if st.successes >= 50 && st.fastLatency <= 1.5*st.slowLatency {
+			factor = 1.2
+			st.successes = 0
+		}
+	}
+
+	if factor != 1 {
+		st.lastAdjust = time.Now()
+	}
+	if l.rdb == nil {
+		if factor != 1 {
+			st.limiter.SetLimit(clamp(float64(st.limiter.Limit())*factor, max))
+		}
+		if pause > 0 {
+			st.blockedUntil = time.Now().Add(pause)
+		}
+	}
+	l.mu.Unlock()
+
+	if l.rdb == nil || (factor == 1 && pause == 0) {
+		return
+	}
+
+	// Shared state: every process sees the new rate and the pause
+	ctx = context.WithoutCancel(ctx)
+	if factor != 1 {
+		adjustScript.Run(ctx, l.rdb, []string{keyPrefix + host + ":rate"},
+			factor, minRate, max, stateTTL.Milliseconds())
+	}
+	if pause > 0 {
+		pauseScript.Run(ctx, l.rdb, []string{keyPrefix + host + ":tat"}, pause.Milliseconds())
+	}
+}
+
+// state must be called with l.mu held
+func (l *Limiter) state(host string, max float64) *hostState {
+	st, ok := l.hosts[host]
+	if !ok {
+		st = &hostState{limiter: rate.NewLimiter(rate.Limit(max), 1)}
+		l.hosts[host] = st
+	}
+	return st
+}
+
+func clamp(r, max float64) rate.Limit {
+	if r > max {
+		r = max
+	}
+	if r < minRate {
+		r = minRate
+	}
+	return rate.Limit(r)
+}
+
+// retryAfter parses delay-seconds or an HTTP date, capped at 5 minutes
+func retryAfter(h http.Header) time.Duration {
+	v := h.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+
+	var d time.Duration
+	if secs, err := strconv.Atoi(v); err == nil {
+		d = time.Duration(secs) * time.Second
+	} else if t, err := http.ParseTime(v); err == nil {
+		d = time.Until(t)
+	}
+
+	if d > 5*time.Minute {
+		d = 5 * time.Minute
+	}
+	if d < 0 {
+		d = 0
+	}
+	return d
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("rate limit wait: %w", ctx.Err())
+	}
+}
@@ -0,0 +1,53 @@
+package ratelimit
+
+import "github.com/redis/go-redis/v9"
+
+// reserveScript books the next slot for a host (GCRA without burst) and
+// returns how many milliseconds the caller must wait before sending.
+// Redis time is used so worker clocks do not need to agree.
+//
+// KEYS[1] theoretical arrival time, KEYS[2] current rate
+// ARGV[1] max rate, ARGV[2] min rate, ARGV[3] ttl ms
+var reserveScript = redis.NewScript(`
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local max = tonumber(ARGV[1])
+local rate = tonumber(redis.call('GET', KEYS[2]) or max)
+if rate > max then rate = max end
+if rate < tonumber(ARGV[2]) then rate = tonumber(ARGV[2]) end
+
+local interval = 1000 / rate
+local tat = tonumber(redis.call('GET', KEYS[1]) or now)
+if tat < now then tat = now end
+
+redis.call('SET', KEYS[1], tostring(tat + interval), 'PX', math.ceil(tat + interval - now) + tonumber(ARGV[3]))
+return math.floor(tat - now)
+`)
+
+// adjustScript multiplies the shared rate of a host, clamped to [min, max]
+//
+// KEYS[1] current rate
+// ARGV[1] factor, ARGV[2] min rate, ARGV[3] max rate, ARGV[4] ttl ms
+var adjustScript = redis.NewScript(`
+local max = tonumber(ARGV[3])
+local rate = tonumber(redis.call('GET', KEYS[1]) or max) * tonumber(ARGV[1])
+if rate > max then rate = max end
+if rate < tonumber(ARGV[2]) then rate = tonumber(ARGV[2]) end
+redis.call('SET', KEYS[1], tostring(rate), 'PX', tonumber(ARGV[4]))
+return tostring(rate)
+`)
+
+// pauseScript pushes the next slot of a host out by a Retry-After delay
+//
+// KEYS[1] theoretical arrival time
+// ARGV[1] pause ms
+var pauseScript = redis.NewScript(`
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local until_ = now + tonumber(ARGV[1])
+local tat = tonumber(redis.call('GET', KEYS[1]) or now)
+if tat < until_ then
+  redis.call('SET', KEYS[1], tostring(until_), 'PX', tonumber(ARGV[1]) + 60000)
+end
+return 0
+`)
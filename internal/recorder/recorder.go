@@ -0,0 +1,246 @@
+// Package recorder streams every scan probe into ClickHouse scan_results.
+// Rows are buffered and flushed in batches from a background goroutine so
+// the scanning hot path never waits on ClickHouse.
+package recorder
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kokuroshesh/bugvay/internal/database"
+	"github.com/kokuroshesh/bugvay/internal/httpclient"
+)
+
+const (
+	bufferSize    = 10000
+	batchSize     = 1000
+	flushInterval = 2 * time.Second
+	snippetSize   = 1024
+)
+
+// Task identifies the scan task a probe belongs to
+type Task struct {
+	ProgramID  int
+	AssetID    int
+	EndpointID int
+	Scanner    string
+}
+
+// Row mirrors a bugvay.scan_results row
+type Row struct {
+	Task
+	ScanType        string // "probe" for requests, "finding" for scanner verdicts
+	Method          string
+	URL             string
+	Payload         string
+	Headers         string
+	StatusCode      int
+	ContentLength   int
+	ResponseTime    time.Duration
+	BodySnippet     string
+	ResponseHeaders string
+	Vulnerable      bool
+	Confidence      float64
+	EvidenceHash    string
+	Evidence        string
+	CreatedAt       time.Time
+}
+
+type Recorder struct {
+	ch       *database.ClickHouseDB
+	workerID string
+	rows     chan Row
+	dropped  atomic.Int64
+	done     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// New starts the background flusher. Close must be called to flush the
+// remaining rows.
+func New(ch *database.ClickHouseDB) *Recorder {
+	host, _ := os.Hostname()
+
+	r := &Recorder{
+		ch:       ch,
+		workerID: fmt.Sprintf("%s-%d", host, os.Getpid()),
+		rows:     make(chan Row, bufferSize),
+		done:     make(chan struct{}),
+	}
+
+	r.wg.Add(1)
+	go r.run()
+	return r
+}
+
+type taskKey struct{}
+
+// WithTask attaches task metadata to the probes made with ctx
+func WithTask(ctx context.Context, t Task) context.Context {
+	return context.WithValue(ctx, taskKey{}, t)
+}
+
+// RecordProbe implements httpclient.ProbeRecorder
+func (r *Recorder) RecordProbe(ctx context.Context, p *httpclient.Probe) {
+	task, _ := ctx.Value(taskKey{}).(Task)
+
+	r.Record(Row{
+		Task:            task,
+		ScanType:        "probe",
+		Method:          p.Method,
+		URL:             p.URL,
+		Payload:         p.Payload,
+		Headers:         encodeHeaders(p.RequestHeaders),
+		StatusCode:      p.Status,
+		ContentLength:   p.ContentLength,
+		ResponseTime:    p.Latency,
+		BodySnippet:     snippet(p.Body),
+		ResponseHeaders: encodeHeaders(p.ResponseHeaders),
+	})
+}
+
+// RecordFinding stores a scanner verdict alongside the probes that led to it
+func (r *Recorder) RecordFinding(ctx context.Context, method, url, payload string, confidence float64, evidenceHash string, evidence map[string]interface{}) {
+	task, _ := ctx.Value(taskKey{}).(Task)
+	ev, _ := json.Marshal(evidence)
+
+	r.Record(Row{
+		Task:         task,
+		ScanType:     "finding",
+		Method:       method,
+		URL:          url,
+		Payload:      payload,
+		Vulnerable:   true,
+		Confidence:   confidence,
+		EvidenceHash: evidenceHash,
+		Evidence:     string(ev),
+	})
+}
+
+// Record queues a row without blocking; rows are dropped when ClickHouse
+// cannot keep up
+func (r *Recorder) Record(row Row) {
+	if row.CreatedAt.IsZero() {
+		row.CreatedAt = time.Now()
+	}
+
+	select {
+	case r.rows <- row:
+	default:
+		if n := r.dropped.Add(1); n%1000 == 1 {
+			log.Printf("recorder: buffer full, %d rows dropped so far", n)
+		}
+	}
+}
+
+// Close flushes buffered rows and stops the flusher
+func (r *Recorder) Close() {
+	close(r.done)
+	r.wg.Wait()
+}
+
+func (r *Recorder) run() {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]Row, 0, batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := r.insert(batch); err != nil {
+			log.Printf("recorder: insert %d rows: %v", len(batch), err)
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case row := <-r.rows:
+			batch = append(batch, row)
+			if len(batch) >= batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case <-r.done:
+			// Drain what is already queued
+			for {
+				select {
+				case row := <-r.rows:
+					batch = append(batch, row)
+					if len(batch) >= batchSize {
+						flush()
+					}
+				default:
+					flush()
+					return
+				}
+			}
+		}
+	}
+}
+
+func (r *Recorder) insert(rows []Row) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	batch, err := r.ch.Conn.PrepareBatch(ctx, `
+		INSERT INTO scan_results (
+			program_id, asset_id, endpoint_id, scanner, scan_type,
+			method, url, payload, headers,
+			status_code, content_length, response_time_ms, body_snippet, response_headers,
+			vulnerable, confidence, evidence_hash, evidence,
+			worker_id, created_at
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("prepare batch: %w", err)
+	}
+
+	for _, row := range rows {
+		err := batch.Append(
+			uint32(row.ProgramID), uint32(row.AssetID), uint32(row.EndpointID), row.Scanner, row.ScanType,
+			row.Method, row.URL, row.Payload, row.Headers,
+			uint16(row.StatusCode), uint32(row.ContentLength), responseTimeMs(row.ResponseTime), row.BodySnippet, row.ResponseHeaders,
+			row.Vulnerable, float32(row.Confidence), row.EvidenceHash, row.Evidence,
+			r.workerID, row.CreatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("append row: %w", err)
+		}
+	}
+
+	return batch.Send()
+}
+
+// responseTimeMs saturates at the UInt16 column maximum
+func responseTimeMs(d time.Duration) uint16 {
+	ms := d.Milliseconds()
+	if ms > 65535 {
+		return 65535
+	}
+	return uint16(ms)
+}
+
+func snippet(body []byte) string {
+	if len(body) > snippetSize {
+		body = body[:snippetSize]
+	}
+	return string(body)
+}
+
+func encodeHeaders(h map[string][]string) string {
+	if len(h) == 0 {
+		return ""
+	}
+	b, _ := json.Marshal(h)
+	return string(b)
+}
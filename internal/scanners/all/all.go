@@ -0,0 +1,14 @@
+// Package all links every scanner module into a binary. Importing it for
+// side effects registers the scanners with the scanners registry.
+package all
+
+import (
+	_ "github.com/kokuroshesh/bugvay/internal/scanners/cmdi"
+	_ "github.com/kokuroshesh/bugvay/internal/scanners/cors"
+	_ "github.com/kokuroshesh/bugvay/internal/scanners/lfi"
+	_ "github.com/kokuroshesh/bugvay/internal/scanners/redirect"
+	_ "github.com/kokuroshesh/bugvay/internal/scanners/sqli"
+	_ "github.com/kokuroshesh/bugvay/internal/scanners/ssrf"
+	_ "github.com/kokuroshesh/bugvay/internal/scanners/ssti"
+	_ "github.com/kokuroshesh/bugvay/internal/scanners/xss"
+)
@@ -0,0 +1,326 @@
+package scanners
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kokuroshesh/bugvay/internal/httpclient"
+)
+
+// BaselineSamples is how many times the original request is sent to learn
+// what varies between identical requests
+const BaselineSamples = 3
+
+// Thresholds above which a response counts as different from the baseline
+const (
+	minLineSimilarity = 0.9
+	minLengthRatio    = 0.95
+	minTimingJitter   = 50 * time.Millisecond
+	timingDeviations  = 3
+)
+
+type mask struct {
+	re   *regexp.Regexp
+	repl string
+}
+
+// dynamicPatterns match content that changes on every request. They are
+// masked before any comparison.
+var dynamicPatterns = []mask{
+	// CSRF and similar tokens in hidden inputs and meta tags
+	{regexp.MustCompile(`(?i)((?:csrf|xsrf|token|nonce|authenticity)[^>]{0,80}?(?:value|content)\s*=\s*["'])[^"']*`), "${1}§"},
+	{regexp.MustCompile(`(?i)((?:value|content)\s*=\s*["'])[^"']*(["'][^>]{0,80}?(?:csrf|xsrf|token|nonce|authenticity))`), "${1}§${2}"},
+	// ISO 8601 and HTTP dates, clock times
+	{regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}(?::\d{2}(?:\.\d+)?)?(?:Z|[+-]\d{2}:?\d{2})?`), "§"},
+	{regexp.MustCompile(`(?i)(?:mon|tue|wed|thu|fri|sat|sun), \d{2} \w{3} \d{4} \d{2}:\d{2}:\d{2}(?: \w+)?`), "§"},
+	{regexp.MustCompile(`\b\d{2}:\d{2}:\d{2}\b`), "§"},
+	// Unix timestamps in seconds or milliseconds, UUIDs
+	{regexp.MustCompile(`\b1\d{9}(?:\d{3})?\b`), "§"},
+	{regexp.MustCompile(`(?i)\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`), "§"},
+	// Nonces, hashes and request IDs
+	{regexp.MustCompile(`(?i)\b[0-9a-f]{16,}\b`), "§"},
+	{regexp.MustCompile(`[A-Za-z0-9+/_-]{32,}={0,2}`), "§"},
+}
+
+// Mask replaces dynamic content in body with a fixed placeholder
+func Mask(body []byte) string {
+	s := string(body)
+	for _, m := range dynamicPatterns {
+		s = m.re.ReplaceAllString(s, m.repl)
+	}
+	return s
+}
+
+// Baseline is what the endpoint's original request normally returns
+type Baseline struct {
+	Status  int
+	Length  int           // mean masked body length
+	Words   int           // mean word count
+	Elapsed time.Duration // mean response time
+	Jitter  time.Duration // standard deviation of the response time
+	Headers []string      // header names present in every sample
+	Body    string        // first sample, unmasked
+	Stable  bool          // samples agreed on status and content
+
+	lengthTolerance int
+	wordTolerance   int
+	lines           map[string]bool // masked lines present in every sample
+	seenHeaders     map[string]bool
+}
+
+// NewBaseline sends the input's original request BaselineSamples times
+func NewBaseline(ctx context.Context, client *httpclient.Scanner, input *ScanInput) (*Baseline, error) {
+	var samples []sample
+	for i := 0; i < BaselineSamples; i++ {
+		req, err := input.NewRequest(ctx, "")
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := client.DoRequest(ctx, req)
+		if err != nil {
+			return nil, fmt.Errorf("baseline request: %w", err)
+		}
+		samples = append(samples, newSample(resp.StatusCode, resp.Header, resp.Body, resp.Elapsed))
+	}
+	return baselineFrom(samples), nil
+}
+
+type sample struct {
+	status  int
+	header  http.Header
+	body    []byte
+	masked  string
+	words   int
+	elapsed time.Duration
+}
+
+func newSample(status int, header http.Header, body []byte, elapsed time.Duration) sample {
+	masked := Mask(body)
+	return sample{
+		status:  status,
+		header:  header,
+		body:    body,
+		masked:  masked,
+		words:   len(strings.Fields(masked)),
+		elapsed: elapsed,
+	}
+}
+
+func baselineFrom(samples []sample) *Baseline {
+	first := samples[0]
+	b := &Baseline{
+		Status:      first.status,
+		Body:        string(first.body),
+		Stable:      true,
+		lines:       lineSet(first.masked),
+		seenHeaders: make(map[string]bool),
+	}
+
+	var lengths, words []float64
+	var elapsed []float64
+	headerCount := make(map[string]int)
+	for _, s := range samples {
+		if s.status != b.Status {
+			b.Stable = false
+		}
+		lengths = append(lengths, float64(len(s.masked)))
+		words = append(words, float64(s.words))
+		elapsed = append(elapsed, float64(s.elapsed))
+		for name := range s.header {
+			headerCount[name]++
+			b.seenHeaders[name] = true
+		}
+
+		// Lines that do not repeat are dynamic even if no pattern caught them
+		current := lineSet(s.masked)
+		for line := range b.lines {
+			if !current[line] {
+				delete(b.lines, line)
+			}
+		}
+	}
+
+	var meanLen, meanWords, meanElapsed float64
+	meanLen, b.lengthTolerance = meanSpread(lengths)
+	meanWords, b.wordTolerance = meanSpread(words)
+	meanElapsed, _ = meanSpread(elapsed)
+	b.Length, b.Words, b.Elapsed = int(meanLen), int(meanWords), time.Duration(meanElapsed)
+	b.Jitter = time.Duration(stddev(elapsed, meanElapsed))
+
+	for name, n := range headerCount {
+		if n == len(samples) {
+			b.Headers = append(b.Headers, name)
+		}
+	}
+	sort.Strings(b.Headers)
+
+	if float64(len(b.lines)) < minLineSimilarity*float64(len(lineSet(first.masked))) || b.lengthTolerance > b.Length/10 {
+		b.Stable = false
+	}
+	return b
+}
+
+// Diff describes how a response deviates from the baseline
+type Diff struct {
+	StatusChanged  bool
+	Status         int
+	LengthRatio    float64 // shorter over longer, after tolerance
+	WordDelta      int     // beyond the spread seen in the baseline
+	LineSimilarity float64 // share of the baseline's stable lines still present
+	MissingHeaders []string
+	NewHeaders     []string
+	TimingDelta    time.Duration // elapsed minus the baseline mean
+	Slow           bool          // beyond normal timing variance
+}
+
+// Significant reports whether the response is a different page rather
+// than the same page with different dynamic content
+func (d *Diff) Significant() bool {
+	return d.StatusChanged || d.LineSimilarity < minLineSimilarity || d.LengthRatio < minLengthRatio
+}
+
+// Compare diffs a response against the baseline. Occurrences of ignore,
+// typically the reflected payload, are removed from body first.
+func (b *Baseline) Compare(resp *httpclient.Response, ignore ...string) *Diff {
+	body := resp.Body
+	for _, s := range ignore {
+		if s != "" {
+			body = bytes.ReplaceAll(body, []byte(s), nil)
+			body = bytes.ReplaceAll(body, []byte(html.EscapeString(s)), nil)
+		}
+	}
+
+	s := newSample(resp.StatusCode, resp.Header, body, resp.Elapsed)
+	d := &Diff{
+		StatusChanged:  s.status != b.Status,
+		Status:         s.status,
+		LengthRatio:    ratioWithin(len(s.masked), b.Length, b.lengthTolerance),
+		LineSimilarity: b.lineSimilarity(s.masked),
+		TimingDelta:    resp.Elapsed - b.Elapsed,
+	}
+	if delta := s.words - b.Words; delta > b.wordTolerance || -delta > b.wordTolerance {
+		d.WordDelta = delta
+	}
+
+	for _, name := range b.Headers {
+		if _, ok := s.header[name]; !ok {
+			d.MissingHeaders = append(d.MissingHeaders, name)
+		}
+	}
+	for name := range s.header {
+		if !b.seenHeaders[name] {
+			d.NewHeaders = append(d.NewHeaders, name)
+		}
+	}
+	sort.Strings(d.NewHeaders)
+
+	jitter := b.Jitter
+	if jitter < minTimingJitter {
+		jitter = minTimingJitter
+	}
+	d.Slow = d.TimingDelta > timingDeviations*jitter
+	return d
+}
+
+// Measurable reports whether an injected delay of d stands out from the
+// baseline's timing variance at all
+func (b *Baseline) Measurable(d time.Duration) bool {
+	return b.Jitter <= d/4
+}
+
+// Delayed reports whether a response that took elapsed was held up by
+// about want: beyond normal variance, by at least 80% of want and by no
+// more than twice it. Much longer points at a timeout or an overloaded
+// host, not at the injected delay.
+func (b *Baseline) Delayed(elapsed, want time.Duration) bool {
+	jitter := b.Jitter
+	if jitter < minTimingJitter {
+		jitter = minTimingJitter
+	}
+	extra := elapsed - b.Elapsed
+	return extra > timingDeviations*jitter && extra >= want*8/10 && extra <= want*2
+}
+
+// Differs is shorthand for Compare(...).Significant()
+func (b *Baseline) Differs(resp *httpclient.Response, ignore ...string) bool {
+	return b.Compare(resp, ignore...).Significant()
+}
+
+func (b *Baseline) lineSimilarity(masked string) float64 {
+	if len(b.lines) == 0 {
+		return 1
+	}
+	current := lineSet(masked)
+	n := 0
+	for line := range b.lines {
+		if current[line] {
+			n++
+		}
+	}
+	return float64(n) / float64(len(b.lines))
+}
+
+// lineSet splits on newlines and tag ends, so minified pages still have
+// something to compare
+func lineSet(masked string) map[string]bool {
+	set := make(map[string]bool)
+	split := func(r rune) bool { return r == '\n' || r == '>' }
+	for _, line := range strings.FieldsFunc(masked, split) {
+		if line = strings.TrimSpace(line); line != "" {
+			set[line] = true
+		}
+	}
+	return set
+}
+
+// ratioWithin is the length ratio of n to mean, ignoring differences the
+// baseline samples already showed
+func ratioWithin(n, mean, tolerance int) float64 {
+	delta := n - mean
+	if delta < 0 {
+		delta = -delta
+	}
+	if delta <= tolerance {
+		return 1
+	}
+	delta -= tolerance
+	longer := mean
+	if n > mean {
+		longer = n
+	}
+	if longer == 0 {
+		return 1
+	}
+	return 1 - float64(delta)/float64(longer)
+}
+
+// meanSpread returns the mean and the largest deviation from it
+func meanSpread(xs []float64) (float64, int) {
+	var sum float64
+	for _, x := range xs {
+		sum += x
+	}
+	mean := sum / float64(len(xs))
+	var spread float64
+	for _, x := range xs {
+		spread = math.Max(spread, math.Abs(x-mean))
+	}
+	return mean, int(math.Ceil(spread))
+}
+
+func stddev(xs []float64, mean float64) float64 {
+	var sum float64
+	for _, x := range xs {
+		sum += (x - mean) * (x - mean)
+	}
+	return math.Sqrt(sum / float64(len(xs)))
+}
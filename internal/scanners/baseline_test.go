@@ -0,0 +1,182 @@
+package scanners
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kokuroshesh/bugvay/internal/httpclient"
+)
+
+func TestMask(t *testing.T) {
+	tests := []struct {
+		name, a, b string
+	}{
+		{"csrf input", `<input type="hidden" name="csrf_token" value="k3j4h5">`, `<input type="hidden" name="csrf_token" value="zz91x">`},
+		{"csrf value first", `<input value="k3j4h5" name="authenticity_token">`, `<input value="q" name="authenticity_token">`},
+		{"meta nonce", `<meta name="csp-nonce" content="abc">`, `<meta name="csp-nonce" content="xyz">`},
+		{"iso date", "at 2024-01-02T03:04:05.123Z", "at 2025-11-12T13:14:15+02:00"},
+		{"http date", "Mon, 02 Jan 2024 03:04:05 GMT", "Tue, 03 Jan 2024 13:14:15 GMT"},
+		{"clock", "rendered 12:00:01", "rendered 23:59:59"},
+		{"unix time", "ts=1700000000 ms=1700000000123", "ts=1711111111 ms=1711111111999"},
+		{"uuid", "req 123e4567-e89b-12d3-a456-426614174000", "req 9f8e7d6c-5b4a-3c2d-1e0f-abcdefabcdef"},
+		{"hex id", "trace 0123456789abcdef01", "trace fedcba9876543210fe"},
+		{"base64 token", "jwt eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9abc", "jwt eyJzdWIiOiIxMjM0NTY3ODkwIiwibmFtZSI6Ikpv"},
+	}
+	for _, tt := range tests {
+		if ma, mb := Mask([]byte(tt.a)), Mask([]byte(tt.b)); ma != mb {
+			t.Errorf("%s: %q and %q mask differently", tt.name, ma, mb)
+		}
+	}
+
+	// Ordinary content is left alone
+	for _, s := range []string{"price 1999 EUR", "id=42", "Hello world", "2024 results"} {
+		if got := Mask([]byte(s)); got != s {
+			t.Errorf("Mask(%q) = %q", s, got)
+		}
+	}
+}
+
+// page renders a product page with fresh dynamic content on every call
+func page(n int, extra string) []byte {
+	return []byte(fmt.Sprintf(`<html><head><meta name="csrf-token" content="tok%dx"></head>
+<body>
+<h1>Products</h1>
+<p>Generated at 2024-05-0%dT10:00:00Z, request %08x-aaaa-bbbb-cccc-%012x</p>
+<ul><li>Widget</li><li>Gadget</li><li>Gizmo</li></ul>
+%s
+<footer>Contact us</footer>
+</body></html>`, n, n, n, n, extra))
+}
+
+func baselineOf(bodies ...[]byte) *Baseline {
+	var samples []sample
+	for i, body := range bodies {
+		h := http.Header{"Content-Type": {"text/html"}, "Date": {"x"}}
+		samples = append(samples, newSample(200, h, body, time.Duration(100+i*2)*time.Millisecond))
+	}
+	return baselineFrom(samples)
+}
+
+func response(status int, body []byte, elapsed time.Duration, header http.Header) *httpclient.Response {
+	if header == nil {
+		header = http.Header{"Content-Type": {"text/html"}, "Date": {"x"}}
+	}
+	return &httpclient.Response{StatusCode: status, Header: header, Body: body, Elapsed: elapsed}
+}
+
+func TestBaseline(t *testing.T) {
+	b := baselineOf(page(1, ""), page(2, ""), page(3, ""))
+	if !b.Stable {
+		t.Fatal("baseline of one page with dynamic content is not stable")
+	}
+	if b.Status != 200 || b.Elapsed != 102*time.Millisecond {
+		t.Errorf("status %d elapsed %s", b.Status, b.Elapsed)
+	}
+	if strings.Join(b.Headers, ",") != "Content-Type,Date" {
+		t.Errorf("headers = %v", b.Headers)
+	}
+
+	tests := []struct {
+		name        string
+		resp        *httpclient.Response
+		ignore      string
+		significant bool
+	}{
+		{"same page, new dynamic content", response(200, page(7, ""), 100*time.Millisecond, nil), "", false},
+		{"reflected payload ignored", response(200, page(7, strings.Repeat("&lt;script&gt;alert(1)&lt;/script&gt;", 10)), 100*time.Millisecond, nil), "<script>alert(1)</script>", false},
+		{"reflected payload not ignored", response(200, page(7, strings.Repeat("&lt;script&gt;alert(1)&lt;/script&gt;", 10)), 100*time.Millisecond, nil), "", true},
+		{"status changed", response(500, page(7, ""), 100*time.Millisecond, nil), "", true},
+		{"error page", response(200, []byte("<html><body>SQL syntax error near ''</body></html>"), 100*time.Millisecond, nil), "", true},
+		{"much longer", response(200, page(7, strings.Repeat("<p>row</p>\n", 200)), 100*time.Millisecond, nil), "", true},
+	}
+	for _, tt := range tests {
+		var ignore []string
+		if tt.ignore != "" {
+			ignore = []string{tt.ignore}
+		}
+		if got := b.Differs(tt.resp, ignore...); got != tt.significant {
+			d := b.Compare(tt.resp, ignore...)
+			t.Errorf("%s: Differs = %v, want %v (%+v)", tt.name, got, tt.significant, d)
+		}
+	}
+}
+
+func TestBaselineCompareDetails(t *testing.T) {
+	b := baselineOf(page(1, ""), page(2, ""), page(3, ""))
+
+	d := b.Compare(response(200, page(4, ""), 2*time.Second, http.Header{"Content-Type": {"text/html"}, "X-Debug": {"1"}}))
+	if !d.Slow || d.TimingDelta < time.Second {
+		t.Errorf("2s response: Slow = %v, delta %s", d.Slow, d.TimingDelta)
+	}
+	if strings.Join(d.MissingHeaders, ",") != "Date" || strings.Join(d.NewHeaders, ",") != "X-Debug" {
+		t.Errorf("missing %v, new %v", d.MissingHeaders, d.NewHeaders)
+	}
+
+	// Within the 50ms floor on a near-zero jitter baseline
+	if d := b.Compare(response(200, page(4, ""), 140*time.Millisecond, nil)); d.Slow {
+		t.Errorf("140ms response counted as slow (delta %s)", d.TimingDelta)
+	}
+}
+
+func TestBaselineUnstable(t *testing.T) {
+	if b := baselineOf(page(1, ""), page(2, ""), []byte("<html>maintenance</html>")); b.Stable {
+		t.Error("baseline with a different sample is stable")
+	}
+
+	var samples []sample
+	for _, status := range []int{200, 503, 200} {
+		samples = append(samples, newSample(status, nil, page(1, ""), 0))
+	}
+	if baselineFrom(samples).Stable {
+		t.Error("baseline with changing status is stable")
+	}
+}
+
+func TestRatioWithin(t *testing.T) {
+	tests := []struct {
+		n, mean, tolerance int
+		want               float64
+	}{
+		{100, 100, 0, 1},
+		{104, 100, 5, 1},
+		{110, 100, 0, 1 - 10.0/110},
+		{90, 100, 5, 0.95},
+		{0, 0, 0, 1},
+	}
+	for _, tt := range tests {
+		if got := ratioWithin(tt.n, tt.mean, tt.tolerance); got != tt.want {
+			t.Errorf("ratioWithin(%d, %d, %d) = %v, want %v", tt.n, tt.mean, tt.tolerance, got, tt.want)
+		}
+	}
+}
+
+func TestBaselineDelayed(t *testing.T) {
+	b := &Baseline{Elapsed: 200 * time.Millisecond, Jitter: 20 * time.Millisecond}
+	want := 3 * time.Second
+	tests := []struct {
+		elapsed time.Duration
+		delayed bool
+	}{
+		{200 * time.Millisecond, false},
+		{2 * time.Second, false}, // under 80% of want
+		{2600 * time.Millisecond, true},
+		{3200 * time.Millisecond, true},
+		{6200 * time.Millisecond, true},
+		{7 * time.Second, false}, // more than twice want: a timeout
+	}
+	for _, tt := range tests {
+		if got := b.Delayed(tt.elapsed, want); got != tt.delayed {
+			t.Errorf("Delayed(%v, %v) = %v, want %v", tt.elapsed, want, got, tt.delayed)
+		}
+	}
+
+	if !b.Measurable(want) {
+		t.Error("20ms jitter should not hide a 3s delay")
+	}
+	if (&Baseline{Jitter: time.Second}).Measurable(want) {
+		t.Error("1s jitter should hide a 3s delay")
+	}
+}
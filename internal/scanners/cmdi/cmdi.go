@@ -0,0 +1,84 @@
+package cmdi
+
+import (
+	"context"
+	"time"
+
+	"github.com/kokuroshesh/bugvay/internal/httpclient"
+	"github.com/kokuroshesh/bugvay/internal/scanners"
+)
+
+type CMDiScanner struct {
+	client *httpclient.Scanner
+}
+
+func init() {
+	scanners.Register(scanners.Registration{
+		Name:    "cmdi",
+		CWE:     78,
+		Timeout: 10 * time.Minute,
+		New:     func(c *httpclient.Scanner) scanners.Scanner { return New(c) },
+	})
+}
+
+func New(client *httpclient.Scanner) *CMDiScanner {
+	return &CMDiScanner{client: client}
+}
+
+func (s *CMDiScanner) Name() string {
+	return "cmdi"
+}
+
+func (s *CMDiScanner) Scan(ctx context.Context, input *scanners.ScanInput) (*scanners.ScanResult, error) {
+	points, err := input.InsertionPoints(scanners.InQuery, scanners.InForm, scanners.InJSON, scanners.InCookie)
+	if err != nil {
+		return nil, err
+	}
+	if len(points) == 0 {
+		return &scanners.ScanResult{Vulnerable: false}, nil
+	}
+
+	baseline, err := scanners.NewBaseline(ctx, s.client, input)
+	if err != nil {
+		return nil, err
+	}
+
+	// Output is conclusive and cheap; timing needs several slow requests
+	for _, point := range points {
+		if result := s.testOutputBased(ctx, point, baseline); result != nil {
+			return result, nil
+		}
+		if result := s.testTimeBased(ctx, point, baseline); result != nil {
+			return result, nil
+		}
+
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+	}
+
+	return &scanners.ScanResult{Vulnerable: false}, nil
+}
+
+// fetch sends the endpoint's request with payload injected at point. A
+// separator that breaks the original command can make the handler fail,
+// and the injected command may still have run, so 5xx responses are kept.
+func (s *CMDiScanner) fetch(ctx context.Context, point scanners.InsertionPoint, payload string) (*httpclient.Response, error) {
+	req, err := point.Request(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	return s.client.DoRequest(httpclient.WithPayload(ctx, payload), req, httpclient.ServerErrorsAsSignal())
+}
+
+func newResult(resp *httpclient.Response, confidence float64, evidence map[string]interface{}, proof string) *scanners.ScanResult {
+	return &scanners.ScanResult{
+		Vulnerable: true,
+		Severity:   "critical",
+		CWE:        78,
+		Evidence:   evidence,
+		Proof:      proof,
+		Confidence: confidence,
+		Response:   resp,
+	}
+}
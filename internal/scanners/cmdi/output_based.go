@@ -0,0 +1,43 @@
+package cmdi
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/kokuroshesh/bugvay/internal/scanners"
+)
+
+// testOutputBased injects an echo whose output differs from the payload
+// text and looks for that output in the response
+func (s *CMDiScanner) testOutputBased(ctx context.Context, point scanners.InsertionPoint, baseline *scanners.Baseline) *scanners.ScanResult {
+	for _, sh := range shells {
+		for _, sep := range separators {
+			if !sep.applies(sh) {
+				continue
+			}
+
+			cmd, want := sh.echo(scanners.Canary(), scanners.RandInt(1000, 9999), scanners.RandInt(1000, 9999))
+			payload := point.Original + sep.wrap(cmd)
+			resp, err := s.fetch(ctx, point, payload)
+			if err != nil || !strings.Contains(string(resp.Body), want) || strings.Contains(baseline.Body, want) {
+				continue
+			}
+
+			return newResult(resp, 0.95, map[string]interface{}{
+				"param":     point.Name,
+				"location":  point.Location,
+				"payload":   payload,
+				"url":       resp.URL,
+				"technique": "output-based",
+				"separator": sep.name,
+				"os":        sh.os,
+				"output":    want,
+				"status":    resp.StatusCode,
+			}, fmt.Sprintf("Injected command output in response:\nURL: %s\nPayload: %q\nSeparator: %s\nExpected output: %s",
+				resp.URL, payload, sep.name, want))
+		}
+	}
+
+	return nil
+}
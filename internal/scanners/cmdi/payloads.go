@@ -0,0 +1,67 @@
+package cmdi
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// separator chains an injected command onto the one the parameter feeds.
+// Payloads are appended to the parameter's original value.
+type separator struct {
+	name    string
+	format  string // %s is the injected command
+	windows bool   // also understood by cmd.exe
+}
+
+var separators = []separator{
+	{name: ";", format: ";%s;"},
+	{name: "|", format: "|%s", windows: true},
+	{name: "||", format: "||%s", windows: true},
+	{name: "&&", format: "&&%s", windows: true},
+	{name: "&", format: "&%s&", windows: true},
+	{name: "backticks", format: "`%s`"},
+	{name: "$()", format: "$(%s)"},
+	{name: "newline", format: "\n%s\n", windows: true},
+
+	// Breaking out of a quoted argument first
+	{name: "';", format: "';%s;'"},
+	{name: "\";", format: "\";%s;\""},
+	{name: "\"&", format: "\"&%s&\"", windows: true},
+}
+
+// shell builds the commands for one family of shells
+type shell struct {
+	os string
+	// echo prints canary followed by something only a shell produces, so
+	// a reflected payload never matches
+	echo  func(canary string, a, b int) (cmd, want string)
+	sleep func(seconds int) string
+}
+
+var shells = []shell{
+	{
+		os: "unix",
+		echo: func(canary string, a, b int) (string, string) {
+			return fmt.Sprintf("echo %s$((%d+%d))", canary, a, b), canary + strconv.Itoa(a+b)
+		},
+		sleep: func(seconds int) string { return fmt.Sprintf("sleep %d", seconds) },
+	},
+	{
+		os: "windows",
+		// cmd.exe drops the caret escape
+		echo: func(canary string, a, b int) (string, string) {
+			return fmt.Sprintf("echo %s^%d^%d", canary, a, b), fmt.Sprintf("%s%d%d", canary, a, b)
+		},
+		// ping waits about a second between echo requests
+		sleep: func(seconds int) string { return fmt.Sprintf("ping -n %d 127.0.0.1", seconds+1) },
+	},
+}
+
+// applies reports whether sep works in sh
+func (sep separator) applies(sh shell) bool {
+	return sh.os == "unix" || sep.windows
+}
+
+func (sep separator) wrap(cmd string) string {
+	return fmt.Sprintf(sep.format, cmd)
+}
@@ -0,0 +1,76 @@
+package cmdi
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kokuroshesh/bugvay/internal/scanners"
+)
+
+const sleepSeconds = 3
+
+// testTimeBased covers commands whose output never reaches the response.
+// Each shell's sleep (ping -n on Windows) is chained with every separator
+// that works there. A separator counts only if the sleep holds the response
+// up, a zero-second sleep does not, doubling it doubles the hold-up and the
+// first sleep repeats, which rules out a slow page or a busy host.
+func (s *CMDiScanner) testTimeBased(ctx context.Context, point scanners.InsertionPoint, baseline *scanners.Baseline) *scanners.ScanResult {
+	delay := time.Duration(sleepSeconds) * time.Second
+	if !baseline.Measurable(delay) {
+		return nil
+	}
+
+	for _, sh := range shells {
+		for _, sep := range separators {
+			if !sep.applies(sh) {
+				continue
+			}
+			payload := func(seconds int) string {
+				return point.Original + sep.wrap(sh.sleep(seconds))
+			}
+
+			first, err := s.fetch(ctx, point, payload(sleepSeconds))
+			if err != nil || !baseline.Delayed(first.Elapsed, delay) {
+				continue
+			}
+
+			zero, err := s.fetch(ctx, point, payload(0))
+			if err != nil || zero.Elapsed >= baseline.Elapsed+delay/2 {
+				continue
+			}
+
+			double, err := s.fetch(ctx, point, payload(2*sleepSeconds))
+			if err != nil || !baseline.Delayed(double.Elapsed, 2*delay) {
+				continue
+			}
+
+			confirm, err := s.fetch(ctx, point, payload(sleepSeconds))
+			if err != nil || !baseline.Delayed(confirm.Elapsed, delay) {
+				continue
+			}
+
+			return newResult(confirm, 0.9, map[string]interface{}{
+				"param":       point.Name,
+				"location":    point.Location,
+				"payload":     payload(sleepSeconds),
+				"url":         confirm.URL,
+				"technique":   "time-based",
+				"separator":   sep.name,
+				"os":          sh.os,
+				"delay_ms":    delay.Milliseconds(),
+				"baseline_ms": baseline.Elapsed.Milliseconds(),
+				"jitter_ms":   baseline.Jitter.Milliseconds(),
+				"elapsed_ms":  []int64{first.Elapsed.Milliseconds(), confirm.Elapsed.Milliseconds()},
+				"zero_ms":     zero.Elapsed.Milliseconds(),
+				"double_ms":   double.Elapsed.Milliseconds(),
+			}, fmt.Sprintf("Time delay injected:\nURL: %s\nPayload: %q\nSeparator: %s (%s)\nBaseline: %dms ± %dms, sleep 0: %dms, sleep %d: %dms / %dms, sleep %d: %dms",
+				confirm.URL, payload(sleepSeconds), sep.name, sh.os,
+				baseline.Elapsed.Milliseconds(), baseline.Jitter.Milliseconds(), zero.Elapsed.Milliseconds(),
+				sleepSeconds, first.Elapsed.Milliseconds(), confirm.Elapsed.Milliseconds(),
+				2*sleepSeconds, double.Elapsed.Milliseconds()))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,133 @@
+package cors
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/kokuroshesh/bugvay/internal/httpclient"
+	"github.com/kokuroshesh/bugvay/internal/scanners"
+)
+
+type CORSScanner struct {
+	client *httpclient.Scanner
+}
+
+func init() {
+	scanners.Register(scanners.Registration{
+		Name:    "cors",
+		CWE:     942,
+		Timeout: 2 * time.Minute,
+		New:     func(c *httpclient.Scanner) scanners.Scanner { return New(c) },
+	})
+}
+
+func New(client *httpclient.Scanner) *CORSScanner {
+	return &CORSScanner{client: client}
+}
+
+func (s *CORSScanner) Name() string {
+	return "cors"
+}
+
+// accepted is a crafted origin the server allowed with credentials
+type accepted struct {
+	origin origin
+	value  string
+	resp   *httpclient.Response
+}
+
+// Scan sends the endpoint's request once per crafted Origin. An origin is
+// accepted when Access-Control-Allow-Origin echoes it and
+// Access-Control-Allow-Credentials is true: only then can another site
+// read the response with the victim's cookies. The most exploitable
+// accepted origin is reported.
+func (s *CORSScanner) Scan(ctx context.Context, input *scanners.ScanInput) (*scanners.ScanResult, error) {
+	target, err := url.Parse(input.URL)
+	if err != nil {
+		return nil, fmt.Errorf("parse url: %w", err)
+	}
+
+	// A random domain label no real site answers for
+	attacker := scanners.Canary()
+	var found []accepted
+	for _, o := range origins {
+		value := o.build(target, attacker)
+		if value == "" {
+			continue
+		}
+
+		resp, err := s.fetch(ctx, input, value)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			continue
+		}
+		if allows(resp, value) {
+			found = append(found, accepted{origin: o, value: value, resp: resp})
+		}
+	}
+
+	if len(found) == 0 {
+		return &scanners.ScanResult{Vulnerable: false}, nil
+	}
+	return newResult(found), nil
+}
+
+// fetch sends the endpoint's original request with an Origin header
+func (s *CORSScanner) fetch(ctx context.Context, input *scanners.ScanInput, origin string) (*httpclient.Response, error) {
+	req, err := input.NewRequest(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Origin", origin)
+	return s.client.DoRequest(httpclient.WithPayload(ctx, origin), req)
+}
+
+// allows reports whether resp grants origin credentialed access. A
+// wildcard does not count: browsers refuse it together with credentials.
+func allows(resp *httpclient.Response, origin string) bool {
+	acao := strings.TrimSpace(resp.Header.Get("Access-Control-Allow-Origin"))
+	acac := strings.TrimSpace(resp.Header.Get("Access-Control-Allow-Credentials"))
+	return strings.EqualFold(acao, origin) && strings.EqualFold(acac, "true")
+}
+
+func newResult(found []accepted) *scanners.ScanResult {
+	worst := found[0]
+	names := make([]string, len(found))
+	for i, a := range found {
+		names[i] = a.origin.name
+	}
+
+	confidence := 0.9
+	if len(found) > 1 {
+		confidence = 0.95 // a pattern, not a one-off allowlist entry
+	}
+
+	return &scanners.ScanResult{
+		Vulnerable: true,
+		Severity:   worst.origin.severity,
+		CWE:        942,
+		Evidence: map[string]interface{}{
+			"param":    "Origin",
+			"location": scanners.InHeader,
+			"payload":  worst.value,
+			"url":      worst.resp.URL,
+			"trick":    worst.origin.name,
+			"accepted": names,
+			"acao":     worst.resp.Header.Get("Access-Control-Allow-Origin"),
+			"acac":     worst.resp.Header.Get("Access-Control-Allow-Credentials"),
+			"status":   worst.resp.StatusCode,
+		},
+		Proof: fmt.Sprintf("Credentialed CORS granted to a crafted origin:\nURL: %s\nOrigin: %s (%s)\nAccess-Control-Allow-Origin: %s\nAccess-Control-Allow-Credentials: %s\nImpact: %s\nAccepted origins: %s",
+			worst.resp.URL, worst.value, worst.origin.name,
+			worst.resp.Header.Get("Access-Control-Allow-Origin"),
+			worst.resp.Header.Get("Access-Control-Allow-Credentials"),
+			worst.origin.impact, strings.Join(names, ", ")),
+		Confidence: confidence,
+		Response:   worst.resp,
+	}
+}
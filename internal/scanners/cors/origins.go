@@ -0,0 +1,77 @@
+package cors
+
+import (
+	"net"
+	"net/url"
+)
+
+// origin is a crafted Origin header and how easily an attacker can send
+// it from a page they control, most exploitable first
+type origin struct {
+	name     string
+	severity string
+	build    func(target *url.URL, attacker string) string // "" to skip
+	impact   string
+}
+
+var origins = []origin{
+	{
+		name:     "arbitrary",
+		severity: "high",
+		build:    func(target *url.URL, attacker string) string { return "https://" + attacker + ".com" },
+		impact:   "any website can read authenticated responses",
+	},
+	{
+		name:     "null",
+		severity: "high",
+		build:    func(target *url.URL, attacker string) string { return "null" },
+		impact:   "any website can read authenticated responses from a sandboxed iframe",
+	},
+	{
+		// Unanchored end match: "evilexample.com" passes a check for "example.com"
+		name:     "suffix",
+		severity: "high",
+		build: func(target *url.URL, attacker string) string {
+			return domainTrick(target, func(host string) string { return "https://" + attacker + host })
+		},
+		impact: "an attacker can register a domain ending in the target's name",
+	},
+	{
+		// Unanchored start match: "example.com.evil.com" passes too
+		name:     "prefix",
+		severity: "high",
+		build: func(target *url.URL, attacker string) string {
+			return domainTrick(target, func(host string) string { return "https://" + host + "." + attacker + ".com" })
+		},
+		impact: "an attacker can register a domain starting with the target's name",
+	},
+	{
+		name:     "subdomain",
+		severity: "medium",
+		build: func(target *url.URL, attacker string) string {
+			return domainTrick(target, func(host string) string { return "https://" + attacker + "." + host })
+		},
+		impact: "any subdomain is trusted; needs XSS or a takeover on one of them",
+	},
+	{
+		name:     "http-downgrade",
+		severity: "medium",
+		build: func(target *url.URL, attacker string) string {
+			if target.Scheme != "https" {
+				return ""
+			}
+			return "http://" + target.Host
+		},
+		impact: "the plain-HTTP origin is trusted; needs a man-in-the-middle position",
+	},
+}
+
+// domainTrick builds an origin from the target's host name, skipping IP
+// targets that have no domain to imitate
+func domainTrick(target *url.URL, build func(host string) string) string {
+	host := target.Hostname()
+	if host == "" || net.ParseIP(host) != nil {
+		return ""
+	}
+	return build(host)
+}
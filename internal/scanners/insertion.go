@@ -0,0 +1,426 @@
+package scanners
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Insertion point locations
+const (
+	InQuery  = "query"
+	InForm   = "body_form"
+	InJSON   = "body_json"
+	InHeader = "header"
+	InCookie = "cookie"
+	InPath   = "path"
+)
+
+// reflectedHeaders are injected even when the endpoint was imported
+// without them; X-* headers are injected when present
+var reflectedHeaders = []string{"Referer", "User-Agent"}
+
+// InsertionPoint is one named place in a ScanInput's request that a payload
+// can replace
+type InsertionPoint struct {
+	Location string
+	Name     string
+	Original string // decoded value before injection
+
+	input *ScanInput
+	index int // path segment index
+}
+
+// Request builds the input's request with payload injected at p
+func (p InsertionPoint) Request(ctx context.Context, payload string) (*http.Request, error) {
+	return p.input.NewRequest(ctx, payload, p)
+}
+
+// InsertionPoints parses in into its injection points, limited to the given
+// locations (all when none are given). Excluded parameters are left out.
+func (in *ScanInput) InsertionPoints(locations ...string) ([]InsertionPoint, error) {
+	u, err := url.Parse(in.URL)
+	if err != nil {
+		return nil, fmt.Errorf("parse url: %w", err)
+	}
+
+	want := func(loc string) bool {
+		if len(locations) == 0 {
+			return true
+		}
+		for _, l := range locations {
+			if l == loc {
+				return true
+			}
+		}
+		return false
+	}
+
+	var points []InsertionPoint
+	add := func(loc, name, original string, index int) {
+		if !in.Excluded(name) {
+			points = append(points, InsertionPoint{Location: loc, Name: name, Original: original, input: in, index: index})
+		}
+	}
+
+	if want(InQuery) {
+		for _, kv := range splitPairs(u.RawQuery) {
+			add(InQuery, kv.name, kv.value, 0)
+		}
+	}
+
+	if in.Body != "" {
+		switch in.bodyType() {
+		case InForm:
+			if want(InForm) {
+				for _, kv := range splitPairs(in.Body) {
+					add(InForm, kv.name, kv.value, 0)
+				}
+			}
+		case InJSON:
+			if want(InJSON) {
+				for _, leaf := range jsonLeaves([]byte(in.Body)) {
+					add(InJSON, leaf.path, leaf.value, 0)
+				}
+			}
+		}
+	}
+
+	if want(InHeader) {
+		headers := append([]string(nil), reflectedHeaders...)
+		var custom []string
+		for name := range in.Headers {
+			if canonical := http.CanonicalHeaderKey(name); strings.HasPrefix(canonical, "X-") {
+				custom = append(custom, canonical)
+			}
+		}
+		sort.Strings(custom)
+		for _, name := range append(headers, custom...) {
+			add(InHeader, name, in.Header(name), 0)
+		}
+	}
+
+	if cookie := in.Header("Cookie"); cookie != "" && want(InCookie) {
+		req := http.Request{Header: http.Header{"Cookie": {cookie}}}
+		for _, c := range req.Cookies() {
+			add(InCookie, c.Name, c.Value, 0)
+		}
+	}
+
+	if want(InPath) {
+		for i, seg := range strings.Split(u.EscapedPath(), "/") {
+			if seg != "" {
+				original, _ := url.PathUnescape(seg)
+				add(InPath, original, original, i)
+			}
+		}
+	}
+
+	return points, nil
+}
+
+// QueryPoint is an insertion point for a query parameter that need not be
+// present in the URL, for fuzzing hidden parameters
+func (in *ScanInput) QueryPoint(name string) InsertionPoint {
+	p := InsertionPoint{Location: InQuery, Name: name, input: in}
+	if u, err := url.Parse(in.URL); err == nil {
+		p.Original = u.Query().Get(name)
+	}
+	return p
+}
+
+// NewRequest builds the input's request with payload injected at every
+// point. Without points it is the original request, for baselines.
+// Everything that is not injected is sent exactly as imported.
+func (in *ScanInput) NewRequest(ctx context.Context, payload string, points ...InsertionPoint) (*http.Request, error) {
+	u, err := url.Parse(in.URL)
+	if err != nil {
+		return nil, fmt.Errorf("parse url: %w", err)
+	}
+	body := in.Body
+	headers := make(http.Header)
+	for k, v := range in.Headers {
+		headers.Set(k, v)
+	}
+
+	for _, p := range points {
+		switch p.Location {
+		case InQuery:
+			u.RawQuery = setPair(u.RawQuery, p.Name, escapeValue(payload, "&#+;"))
+		case InForm:
+			body = setPair(body, p.Name, escapeValue(payload, "&#+;"))
+		case InJSON:
+			b, err := setJSONLeaf([]byte(body), p.Name, payload)
+			if err != nil {
+				return nil, err
+			}
+			body = string(b)
+		case InHeader:
+			headers.Set(p.Name, payload)
+		case InCookie:
+			headers.Set("Cookie", setCookie(headers.Get("Cookie"), p.Name, escapeValue(payload, ";,\"\\")))
+		case InPath:
+			segs := strings.Split(u.EscapedPath(), "/")
+			if p.index < len(segs) {
+				// Everything url.URL would re-escape, so RawPath is kept
+				segs[p.index] = escapeValue(payload, "?#\"<>`{}|^")
+			}
+			u.RawPath = strings.Join(segs, "/")
+			u.Path, _ = url.PathUnescape(u.RawPath)
+		}
+	}
+
+	method := in.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	var r io.Reader
+	if body != "" {
+		r = strings.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), r)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range headers {
+		req.Header[k] = v
+	}
+	return req, nil
+}
+
+// Header returns the input header name, matched case-insensitively
+func (in *ScanInput) Header(name string) string {
+	for k, v := range in.Headers {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	return ""
+}
+
+// bodyType tells form and JSON bodies apart by Content-Type, falling back
+// to the shape of the body
+func (in *ScanInput) bodyType() string {
+	ct := strings.ToLower(in.Header("Content-Type"))
+	switch {
+	case strings.Contains(ct, "json"):
+		return InJSON
+	case strings.Contains(ct, "x-www-form-urlencoded"):
+		return InForm
+	case ct == "":
+		trimmed := strings.TrimSpace(in.Body)
+		if strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+			return InJSON
+		}
+		return InForm
+	}
+	return ""
+}
+
+// escapeValue percent-encodes whitespace, control bytes, the given
+// delimiters and stray '%' signs. Existing %XX sequences and everything else
+// are kept, so pre-encoded payloads reach the server exactly as written.
+func escapeValue(v, delims string) string {
+	var b strings.Builder
+	for i := 0; i < len(v); i++ {
+		c := v[i]
+		switch {
+		case c == '%' && i+2 < len(v) && isHex(v[i+1]) && isHex(v[i+2]):
+			b.WriteByte(c)
+		case c <= ' ' || c >= 0x7f || c == '%' || c == '\\' || strings.IndexByte(delims, c) >= 0:
+			fmt.Fprintf(&b, "%%%02X", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+type pair struct {
+	name, value string
+}
+
+// splitPairs decodes an urlencoded string in order, reporting repeated
+// names once
+func splitPairs(raw string) []pair {
+	var pairs []pair
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(raw, "&") {
+		if part == "" {
+			continue
+		}
+		k, v, _ := strings.Cut(part, "=")
+		name, err := url.QueryUnescape(k)
+		if err != nil || seen[name] {
+			continue
+		}
+		seen[name] = true
+		value, _ := url.QueryUnescape(v)
+		pairs = append(pairs, pair{name, value})
+	}
+	return pairs
+}
+
+// setPair replaces the first value of name in an urlencoded string, or
+// appends it, leaving every other byte as it was
+func setPair(raw, name, escaped string) string {
+	parts := strings.Split(raw, "&")
+	for i, part := range parts {
+		k, _, _ := strings.Cut(part, "=")
+		if decoded, err := url.QueryUnescape(k); err == nil && decoded == name {
+			parts[i] = k + "=" + escaped
+			return strings.Join(parts, "&")
+		}
+	}
+	if raw != "" {
+		raw += "&"
+	}
+	return raw + url.QueryEscape(name) + "=" + escaped
+}
+
+// setCookie swaps one cookie's value, leaving the others byte-for-byte
+func setCookie(header, name, escaped string) string {
+	parts := strings.Split(header, ";")
+	for i, part := range parts {
+		k, _, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if ok && k == name {
+			parts[i] = " " + name + "=" + escaped
+		}
+	}
+	return strings.TrimSpace(strings.Join(parts, ";"))
+}
+
+type jsonLeaf struct {
+	path       string
+	value      string
+	start, end int // raw value bytes in the document
+}
+
+type jsonFrame struct {
+	array   bool
+	key     string
+	index   int
+	wantKey bool
+}
+
+// jsonLeaves lists the string, number and bool leaves of a JSON document
+// with their dotted paths (array elements by index) and byte offsets
+func jsonLeaves(doc []byte) []jsonLeaf {
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	dec.UseNumber()
+
+	var stack []*jsonFrame
+	var leaves []jsonLeaf
+	top := func() *jsonFrame {
+		if len(stack) == 0 {
+			return nil
+		}
+		return stack[len(stack)-1]
+	}
+	// valueDone makes an enclosing object expect its next key
+	valueDone := func() {
+		if f := top(); f != nil && !f.array {
+			f.wantKey = true
+		}
+	}
+
+	for {
+		before := int(dec.InputOffset())
+		tok, err := dec.Token()
+		if err != nil {
+			return leaves
+		}
+		f := top()
+
+		if f != nil && f.wantKey {
+			if _, ok := tok.(json.Delim); ok { // closing '}'
+				stack = stack[:len(stack)-1]
+				valueDone()
+				continue
+			}
+			f.key, _ = tok.(string)
+			f.wantKey = false
+			continue
+		}
+
+		if f != nil && f.array {
+			if d, ok := tok.(json.Delim); !ok || d != ']' {
+				f.index++
+			}
+		}
+
+		if d, ok := tok.(json.Delim); ok {
+			if d == '{' || d == '[' {
+				stack = append(stack, &jsonFrame{array: d == '[', index: -1, wantKey: d == '{'})
+			} else {
+				stack = stack[:len(stack)-1]
+				valueDone()
+			}
+			continue
+		}
+
+		var value string
+		switch v := tok.(type) {
+		case string:
+			value = v
+		case json.Number:
+			value = v.String()
+		case bool:
+			value = strconv.FormatBool(v)
+		}
+
+		if tok != nil && len(stack) > 0 {
+			var path []string
+			for _, fr := range stack {
+				if fr.array {
+					path = append(path, strconv.Itoa(fr.index))
+				} else {
+					path = append(path, fr.key)
+				}
+			}
+
+			end := int(dec.InputOffset())
+			start := before
+			for start < end && strings.IndexByte(" \t\r\n:,", doc[start]) >= 0 {
+				start++
+			}
+			leaves = append(leaves, jsonLeaf{path: strings.Join(path, "."), value: value, start: start, end: end})
+		}
+		valueDone()
+	}
+}
+
+// setJSONLeaf replaces the leaf at path with payload as a JSON string,
+// splicing it into the original bytes so formatting and key order survive
+func setJSONLeaf(doc []byte, path, payload string) ([]byte, error) {
+	for _, leaf := range jsonLeaves(doc) {
+		if leaf.path != path {
+			continue
+		}
+
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(payload); err != nil {
+			return nil, err
+		}
+		value := bytes.TrimRight(buf.Bytes(), "\n")
+
+		out := make([]byte, 0, len(doc)+len(value))
+		out = append(out, doc[:leaf.start]...)
+		out = append(out, value...)
+		return append(out, doc[leaf.end:]...), nil
+	}
+	return nil, fmt.Errorf("json field %q not found", path)
+}
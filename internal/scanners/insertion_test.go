@@ -0,0 +1,273 @@
+package scanners
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func pointKeys(points []InsertionPoint) []string {
+	keys := make([]string, len(points))
+	for i, p := range points {
+		keys[i] = p.Location + " " + p.Name + "=" + p.Original
+	}
+	return keys
+}
+
+func TestInsertionPoints(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     ScanInput
+		locations []string
+		want      []string
+	}{
+		{
+			name:      "query in order, repeated names once",
+			input:     ScanInput{URL: "https://example.com/s?q=a%20b&page=2&q=c"},
+			locations: []string{InQuery},
+			want:      []string{"query q=a b", "query page=2"},
+		},
+		{
+			name: "form body",
+			input: ScanInput{
+				URL:     "https://example.com/login",
+				Method:  "POST",
+				Headers: map[string]string{"Content-Type": "application/x-www-form-urlencoded"},
+				Body:    "user=alice&pass=s%26cret",
+			},
+			locations: []string{InForm, InJSON},
+			want:      []string{"body_form user=alice", "body_form pass=s&cret"},
+		},
+		{
+			name: "json body leaves by path",
+			input: ScanInput{
+				URL:     "https://example.com/api",
+				Method:  "POST",
+				Headers: map[string]string{"Content-Type": "application/json; charset=utf-8"},
+				Body:    `{"name":"alice","age":30,"tags":["a","b"],"addr":{"city":"Oslo","zip":null},"admin":false}`,
+			},
+			locations: []string{InForm, InJSON},
+			want: []string{
+				"body_json name=alice", "body_json age=30", "body_json tags.0=a", "body_json tags.1=b",
+				"body_json addr.city=Oslo", "body_json admin=false",
+			},
+		},
+		{
+			name:      "json sniffed without content type",
+			input:     ScanInput{URL: "https://example.com/api", Method: "POST", Body: ` [{"id":1}]`},
+			locations: []string{InJSON},
+			want:      []string{"body_json 0.id=1"},
+		},
+		{
+			name: "headers and cookies",
+			input: ScanInput{
+				URL:     "https://example.com/",
+				Headers: map[string]string{"x-forwarded-host": "a", "Accept": "*/*", "Cookie": "sid=1; theme=dark"},
+			},
+			locations: []string{InHeader, InCookie},
+			want: []string{
+				"header Referer=", "header User-Agent=", "header X-Forwarded-Host=a",
+				"cookie sid=1", "cookie theme=dark",
+			},
+		},
+		{
+			name:      "path segments",
+			input:     ScanInput{URL: "https://example.com/users/42/a%20b"},
+			locations: []string{InPath},
+			want:      []string{"path users=users", "path 42=42", "path a b=a b"},
+		},
+		{
+			name: "excluded parameters are left out",
+			input: ScanInput{
+				URL:            "https://example.com/?q=1&csrf=x",
+				Headers:        map[string]string{"Cookie": "CSRF=y; a=b"},
+				ExcludedParams: []string{"csrf"},
+			},
+			locations: []string{InQuery, InCookie},
+			want:      []string{"query q=1", "cookie a=b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			points, err := tt.input.InsertionPoints(tt.locations...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := pointKeys(points); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("points = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// valueAt reads back the value at point from a built request
+func valueAt(t *testing.T, req *http.Request, p InsertionPoint) string {
+	t.Helper()
+	switch p.Location {
+	case InQuery:
+		return req.URL.Query().Get(p.Name)
+	case InForm:
+		body, _ := io.ReadAll(req.Body)
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			t.Fatalf("form body %q: %v", body, err)
+		}
+		return values.Get(p.Name)
+	case InJSON:
+		body, _ := io.ReadAll(req.Body)
+		for _, leaf := range jsonLeaves(body) {
+			if leaf.path == p.Name {
+				return leaf.value
+			}
+		}
+		t.Fatalf("json body %s lost %s", body, p.Name)
+	case InHeader:
+		return req.Header.Get(p.Name)
+	case InCookie:
+		c, err := req.Cookie(p.Name)
+		if err != nil {
+			t.Fatalf("cookie %s: %v", p.Name, err)
+		}
+		v, _ := url.PathUnescape(c.Value)
+		return v
+	case InPath:
+		return strings.Split(req.URL.Path, "/")[p.index]
+	}
+	return ""
+}
+
+// Every point rebuilds into a request that carries the payload at that
+// point, and only there
+func TestNewRequestRoundTrip(t *testing.T) {
+	inputs := []ScanInput{
+		{
+			URL:     "https://example.com/users/42?q=test&page=2",
+			Headers: map[string]string{"Cookie": "sid=abc; theme=dark", "X-Api-Version": "1"},
+		},
+		{
+			URL:     "https://example.com/login",
+			Method:  "POST",
+			Headers: map[string]string{"Content-Type": "application/x-www-form-urlencoded"},
+			Body:    "user=alice&pass=secret",
+		},
+		{
+			URL:     "https://example.com/api",
+			Method:  "PUT",
+			Headers: map[string]string{"Content-Type": "application/json"},
+			Body:    "{\n  \"name\": \"alice\",\n  \"roles\": [\"user\", 2]\n}",
+		},
+	}
+	payloads := []string{"plain", `'"><svg onload=alert(1)>`, "a&b=c;d e+f", "100%", "%27 pre-encoded", "ü\\"}
+
+	for _, input := range inputs {
+		points, err := input.InsertionPoints(InQuery, InForm, InJSON, InCookie, InPath, InHeader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range points {
+			if p.Location == InHeader && p.Name != "X-Api-Version" {
+				continue
+			}
+			for _, payload := range payloads {
+				req, err := p.Request(context.Background(), payload)
+				if err != nil {
+					t.Fatalf("%s %s: %v", p.Location, p.Name, err)
+				}
+
+				want := payload
+				if strings.Contains(payload, "%27") && p.Location != InJSON && p.Location != InHeader {
+					want = strings.Replace(payload, "%27", "'", 1) // sent as written, decoded by the server
+				}
+				if got := valueAt(t, req, p); got != want {
+					t.Errorf("%s %s: payload %q read back as %q", p.Location, p.Name, payload, got)
+				}
+
+				// Every other point keeps its original value
+				for _, other := range points {
+					if other.Location == p.Location && other.Name == p.Name || other.Location == InHeader {
+						continue
+					}
+					req, _ := p.Request(context.Background(), payload)
+					if got := valueAt(t, req, other); got != other.Original {
+						t.Errorf("injecting %s %s changed %s %s to %q", p.Location, p.Name, other.Location, other.Name, got)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestNewRequestOriginal(t *testing.T) {
+	in := ScanInput{
+		URL:     "https://example.com/a?b=1",
+		Method:  "POST",
+		Headers: map[string]string{"Content-Type": "application/json"},
+		Body:    `{"x": 1}`,
+	}
+	req, err := in.NewRequest(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(req.Body)
+	if req.Method != "POST" || req.URL.String() != in.URL || string(body) != in.Body || req.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("original request changed: %s %s %q %v", req.Method, req.URL, body, req.Header)
+	}
+
+	in.Method = ""
+	req, _ = in.NewRequest(context.Background(), "")
+	if req.Method != http.MethodGet {
+		t.Errorf("method = %s, want GET by default", req.Method)
+	}
+}
+
+// Spliced JSON keeps formatting and key order, and stays valid
+func TestSetJSONLeaf(t *testing.T) {
+	doc := "{\n  \"b\": 1,\n  \"a\": {\"c\": [true, \"x\"]}\n}"
+	tests := []struct {
+		path, payload, want string
+	}{
+		{"b", "v", "{\n  \"b\": \"v\",\n  \"a\": {\"c\": [true, \"x\"]}\n}"},
+		{"a.c.1", `<"q">`, "{\n  \"b\": 1,\n  \"a\": {\"c\": [true, \"<\\\"q\\\">\"]}\n}"},
+		{"a.c.0", "", "{\n  \"b\": 1,\n  \"a\": {\"c\": [\"\", \"x\"]}\n}"},
+	}
+	for _, tt := range tests {
+		out, err := setJSONLeaf([]byte(doc), tt.path, tt.payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != tt.want {
+			t.Errorf("set %s:\n got %s\nwant %s", tt.path, out, tt.want)
+		}
+		if !json.Valid(out) {
+			t.Errorf("set %s: invalid JSON %s", tt.path, out)
+		}
+	}
+
+	if _, err := setJSONLeaf([]byte(doc), "missing", "v"); err == nil {
+		t.Error("missing path: want error")
+	}
+}
+
+func TestEscapeValue(t *testing.T) {
+	tests := []struct {
+		in, delims, want string
+	}{
+		{"abc", "&", "abc"},
+		{"a b&c", "&", "a%20b%26c"},
+		{"%27", "&", "%27"},
+		{"100%", "&", "100%25"},
+		{"%zz", "", "%25zz"},
+		{"a\\b\n", "", "a%5Cb%0A"},
+	}
+	for _, tt := range tests {
+		if got := escapeValue(tt.in, tt.delims); got != tt.want {
+			t.Errorf("escapeValue(%q, %q) = %q, want %q", tt.in, tt.delims, got, tt.want)
+		}
+	}
+}
@@ -2,6 +2,9 @@ package scanners
 
 import (
 	"context"
+	"strings"
+
+	"github.com/kokuroshesh/bugvay/internal/httpclient"
 )
 
 // Scanner interface for all scanner modules
@@ -11,11 +14,22 @@ type Scanner interface {
 }
 
 type ScanInput struct {
-	EndpointID int
-	URL        string
-	Method     string
-	Headers    map[string]string
-	Body       string
+	EndpointID     int
+	URL            string
+	Method         string
+	Headers        map[string]string
+	Body           string
+	ExcludedParams []string // parameters the program forbids fuzzing
+}
+
+// Excluded reports whether param must be left untouched
+func (in *ScanInput) Excluded(param string) bool {
+	for _, p := range in.ExcludedParams {
+		if strings.EqualFold(p, param) {
+			return true
+		}
+	}
+	return false
 }
 
 type ScanResult struct {
@@ -25,4 +39,10 @@ type ScanResult struct {
 	Evidence   map[string]interface{}
 	Proof      string
 	Confidence float64
+	Response   *httpclient.Response // exchange that proves the finding, if any
+
+	// Pending lists out-of-band tokens that may still receive a callback.
+	// A result that is not Vulnerable but has Pending tokens is stored as
+	// a pending finding, confirmed when one of them does.
+	Pending []string
 }
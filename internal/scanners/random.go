@@ -0,0 +1,23 @@
+package scanners
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"math/big"
+)
+
+// Canary returns a fresh marker for a payload: "bv" and 10 hex characters,
+// letters and digits only so no encoding changes it, and unlikely enough
+// that finding it in a response means the payload got there
+func Canary() string {
+	b := make([]byte, 5)
+	rand.Read(b)
+	return "bv" + hex.EncodeToString(b)
+}
+
+// RandInt returns a random integer in [min, max], for operands whose
+// result must not be guessable from an earlier probe
+func RandInt(min, max int) int {
+	n, _ := rand.Int(rand.Reader, big.NewInt(int64(max-min+1)))
+	return min + int(n.Int64())
+}
@@ -0,0 +1,18 @@
+package scanners
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCanary(t *testing.T) {
+	a, b := Canary(), Canary()
+	if a == b || len(a) != 12 || !strings.HasPrefix(a, "bv") {
+		t.Errorf("Canary() = %q, %q", a, b)
+	}
+	for i := 0; i < 100; i++ {
+		if n := RandInt(3, 5); n < 3 || n > 5 {
+			t.Fatalf("RandInt(3, 5) = %d", n)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package redirect
+
+import "strings"
+
+// canaryHost is the destination every payload tries to redirect to. It is a
+// reserved name, so a hit can only come from our own payload.
+const canaryHost = "bugvay-canary.example"
+
+// redirectParams are parameter names commonly used for post-action redirects
+var redirectParams = []string{
+	"url", "redirect", "redirect_url", "redirect_uri", "redirectUrl", "redir",
+	"next", "return", "returnUrl", "return_url", "returnTo", "return_to",
+	"r", "u", "dest", "destination", "target", "continue", "success_url",
+	"callback", "jump", "link", "forward", "goto", "out", "view", "to", "go",
+}
+
+// payloadTemplates are sent verbatim in the query string. {canary} is the
+// canary host, {target} the scanned host.
+var payloadTemplates = []string{
+	// Absolute
+	"https://{canary}/",
+	"http://{canary}/",
+	"https:{canary}",
+
+	// Protocol-relative and slash confusion
+	"//{canary}/",
+	"///{canary}/",
+	"/\\{canary}/",
+	"\\\\{canary}/",
+	"/%2F{canary}/",
+	"/%5C{canary}/",
+
+	// @ and subdomain bypasses for allowlist checks
+	"https://{target}@{canary}/",
+	"//{target}@{canary}/",
+	"https://{target}.{canary}/",
+	"https://{canary}/{target}",
+	"https://{canary}?{target}",
+	"https://{canary}%23.{target}/",
+
+	// Encoded
+	"https%3A%2F%2F{canary}%2F",
+	"https%253A%252F%252F{canary}%252F",
+	"%2F%2F{canary}%2F",
+	"https:%5C%5C{canary}/",
+	"%09//{canary}/",
+}
+
+func buildPayloads(target string) []string {
+	payloads := make([]string, 0, len(payloadTemplates))
+	for _, t := range payloadTemplates {
+		p := strings.ReplaceAll(t, "{canary}", canaryHost)
+		p = strings.ReplaceAll(p, "{target}", target)
+		payloads = append(payloads, p)
+	}
+	return payloads
+}
+
+func isRedirectParam(name string) bool {
+	for _, p := range redirectParams {
+		if strings.EqualFold(p, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// looksLikeURL reports whether a parameter value is a URL or absolute path,
+// which makes the parameter a redirect candidate regardless of its name
+func looksLikeURL(value string) bool {
+	v := strings.ToLower(value)
+	return strings.HasPrefix(v, "http://") || strings.HasPrefix(v, "https://") ||
+		strings.HasPrefix(v, "//") || (strings.HasPrefix(v, "/") && len(v) > 1)
+}
@@ -0,0 +1,221 @@
+package redirect
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/kokuroshesh/bugvay/internal/httpclient"
+	"github.com/kokuroshesh/bugvay/internal/scanners"
+)
+
+type RedirectScanner struct {
+	client *httpclient.Scanner
+}
+
+func init() {
+	scanners.Register(scanners.Registration{
+		Name:    "redirect",
+		CWE:     601,
+		Timeout: 5 * time.Minute,
+		New:     func(c *httpclient.Scanner) scanners.Scanner { return New(c) },
+	})
+}
+
+// New expects a client that does not follow redirects (httpclient.NewScanner)
+func New(client *httpclient.Scanner) *RedirectScanner {
+	return &RedirectScanner{client: client}
+}
+
+func (s *RedirectScanner) Name() string {
+	return "redirect"
+}
+
+var (
+	metaRefreshRe = regexp.MustCompile(`(?is)<meta[^>]+http-equiv\s*=\s*["']?refresh["']?[^>]*content\s*=\s*["']?\s*\d*\s*;?\s*url\s*=\s*([^"'>\s]+)`)
+	jsLocationRe  = regexp.MustCompile(`(?i)(?:window\.|document\.|top\.|self\.|parent\.)?location(?:\.href)?\s*=\s*["']([^"']+)["']`)
+	jsNavigateRe  = regexp.MustCompile(`(?i)location\.(?:replace|assign)\(\s*["']([^"']+)["']`)
+)
+
+type detection struct {
+	source     string
+	location   string
+	confidence float64
+}
+
+func (s *RedirectScanner) Scan(ctx context.Context, input *scanners.ScanInput) (*scanners.ScanResult, error) {
+	u, err := url.Parse(input.URL)
+	if err != nil {
+		return nil, fmt.Errorf("parse url: %w", err)
+	}
+
+	payloads := buildPayloads(u.Hostname())
+	points, err := input.InsertionPoints(scanners.InQuery, scanners.InForm)
+	if err != nil {
+		return nil, err
+	}
+
+	// Existing parameters that look like redirect targets
+	var candidates []scanners.InsertionPoint
+	present := make(map[string]bool)
+	for _, point := range points {
+		present[point.Name] = true
+		if isRedirectParam(point.Name) || looksLikeURL(point.Original) {
+			candidates = append(candidates, point)
+		}
+	}
+
+	for _, point := range candidates {
+		for _, payload := range payloads {
+			if result := s.test(ctx, input, point, payload); result != nil {
+				return result, nil
+			}
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+	}
+
+	// Hidden parameters: try all missing names at once, then narrow down
+	var hidden []scanners.InsertionPoint
+	for _, param := range redirectParams {
+		if !present[param] && !input.Excluded(param) {
+			hidden = append(hidden, input.QueryPoint(param))
+		}
+	}
+	if len(hidden) == 0 {
+		return &scanners.ScanResult{Vulnerable: false}, nil
+	}
+
+	// Only a single parameter is ever reported, so the finding names the
+	// one that redirects and not the whole batch
+	for _, payload := range payloads[:4] {
+		if _, _, d := s.probe(ctx, input, payload, hidden...); d == nil {
+			continue
+		}
+		for _, point := range hidden {
+			if result := s.test(ctx, input, point, payload); result != nil {
+				return result, nil
+			}
+		}
+	}
+
+	return &scanners.ScanResult{Vulnerable: false}, nil
+}
+
+// test injects payload at point and reports a redirect to the canary host
+func (s *RedirectScanner) test(ctx context.Context, input *scanners.ScanInput, point scanners.InsertionPoint, payload string) *scanners.ScanResult {
+	testURL, resp, d := s.probe(ctx, input, payload, point)
+	if d == nil {
+		return nil
+	}
+
+	return &scanners.ScanResult{
+		Vulnerable: true,
+		Severity:   "medium",
+		CWE:        601,
+		Evidence: map[string]interface{}{
+			"param":       point.Name,
+			"location":    point.Location,
+			"payload":     payload,
+			"url":         testURL,
+			"source":      d.source,
+			"redirect_to": d.location,
+			"status":      resp.StatusCode,
+		},
+		Proof: fmt.Sprintf("Redirect to canary host via %s:\nURL: %s\nInjected: %s %s\nPayload: %s\nRedirect to: %s\nStatus: %d",
+			d.source, testURL, point.Location, point.Name, payload, d.location, resp.StatusCode),
+		Confidence: d.confidence,
+		Response:   resp,
+	}
+}
+
+// probe sends payload at every one of points and looks for a redirect to
+// the canary host in the response
+func (s *RedirectScanner) probe(ctx context.Context, input *scanners.ScanInput, payload string, points ...scanners.InsertionPoint) (string, *httpclient.Response, *detection) {
+	req, err := input.NewRequest(ctx, payload, points...)
+	if err != nil {
+		return "", nil, nil
+	}
+	testURL := req.URL.String()
+
+	resp, err := s.client.DoRequest(httpclient.WithPayload(ctx, payload), req)
+	if err != nil {
+		return testURL, nil, nil
+	}
+	return testURL, resp, detect(resp)
+}
+
+// detect checks every place a browser would take a redirect from
+func detect(resp *httpclient.Response) *detection {
+	if resp.StatusCode >= 300 && resp.StatusCode < 400 {
+		if loc := resp.Header.Get("Location"); pointsToCanary(loc) {
+			return &detection{source: "location-header", location: loc, confidence: 0.95}
+		}
+	}
+
+	if refresh := resp.Header.Get("Refresh"); refresh != "" {
+		if i := strings.Index(strings.ToLower(refresh), "url="); i >= 0 {
+			loc := strings.Trim(refresh[i+4:], `"' `)
+			if pointsToCanary(loc) {
+				return &detection{source: "refresh-header", location: loc, confidence: 0.9}
+			}
+		}
+	}
+
+	bodyStr := string(resp.Body)
+	for _, m := range metaRefreshRe.FindAllStringSubmatch(bodyStr, -1) {
+		if loc := html.UnescapeString(m[1]); pointsToCanary(loc) {
+			return &detection{source: "meta-refresh", location: loc, confidence: 0.85}
+		}
+	}
+
+	for _, re := range []*regexp.Regexp{jsLocationRe, jsNavigateRe} {
+		for _, m := range re.FindAllStringSubmatch(bodyStr, -1) {
+			if loc := m[1]; pointsToCanary(loc) {
+				return &detection{source: "javascript", location: loc, confidence: 0.7}
+			}
+		}
+	}
+
+	return nil
+}
+
+// pointsToCanary resolves loc the way a browser would and checks whether
+// it navigates to the canary host
+func pointsToCanary(loc string) bool {
+	loc = strings.TrimSpace(loc)
+	if loc == "" {
+		return false
+	}
+
+	// Browsers treat backslashes as slashes and strip tabs/newlines
+	loc = strings.ReplaceAll(loc, "\\", "/")
+	loc = strings.NewReplacer("\t", "", "\n", "", "\r", "").Replace(loc)
+
+	// "https:host" is treated as "https://host" by browsers
+	lower := strings.ToLower(loc)
+	for _, scheme := range []string{"http:", "https:"} {
+		if strings.HasPrefix(lower, scheme) && !strings.HasPrefix(lower, scheme+"//") {
+			loc = scheme + "//" + strings.TrimLeft(loc[len(scheme):], "/")
+		}
+	}
+	if strings.HasPrefix(loc, "///") {
+		loc = "//" + strings.TrimLeft(loc, "/")
+	}
+
+	target, err := url.Parse(loc)
+	if err != nil {
+		return false
+	}
+	if target.Scheme != "" && target.Scheme != "http" && target.Scheme != "https" {
+		return false
+	}
+
+	host := strings.ToLower(target.Hostname())
+	return host == canaryHost || strings.HasSuffix(host, "."+canaryHost)
+}
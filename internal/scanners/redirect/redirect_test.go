@@ -0,0 +1,70 @@
+package redirect
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kokuroshesh/bugvay/internal/config"
+	"github.com/kokuroshesh/bugvay/internal/httpclient"
+	"github.com/kokuroshesh/bugvay/internal/ratelimit"
+	"github.com/kokuroshesh/bugvay/internal/scanners"
+	"github.com/kokuroshesh/bugvay/internal/services"
+)
+
+func TestScanNarrowsHiddenParam(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if next := r.URL.Query().Get("next"); next != "" {
+			http.Redirect(w, r, next, http.StatusFound)
+		}
+	}))
+	defer srv.Close()
+
+	client := httpclient.NewScanner(ratelimit.New(nil, 1000), config.ScannerConfig{Timeout: 5})
+	result, err := New(client).Scan(context.Background(), &scanners.ScanInput{URL: srv.URL + "/login", Method: http.MethodGet})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Vulnerable {
+		t.Fatal("redirect through hidden parameter not found")
+	}
+	if result.Evidence["param"] != "next" || result.Evidence["location"] != scanners.InQuery {
+		t.Errorf("reported %v in %v, want next in %s", result.Evidence["param"], result.Evidence["location"], scanners.InQuery)
+	}
+}
+
+// Each payload redirects somewhere else; the finding must still be one
+func TestPayloadsShareFingerprint(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, r.URL.Query().Get("next"), http.StatusFound)
+	}))
+	defer srv.Close()
+
+	client := httpclient.NewScanner(ratelimit.New(nil, 1000), config.ScannerConfig{Timeout: 5})
+	s := New(client)
+	input := &scanners.ScanInput{URL: srv.URL + "/login?next=/home", Method: http.MethodGet}
+	points, err := input.InsertionPoints(scanners.InQuery)
+	if err != nil || len(points) != 1 {
+		t.Fatalf("InsertionPoints = %v, %v", points, err)
+	}
+
+	hashes := make(map[string]bool)
+	destinations := make(map[interface{}]bool)
+	for _, payload := range buildPayloads("127.0.0.1")[:2] {
+		result := s.test(context.Background(), input, points[0], payload)
+		if result == nil {
+			t.Fatalf("payload %q not detected", payload)
+		}
+		destinations[result.Evidence["redirect_to"]] = true
+		param, _ := result.Evidence["param"].(string)
+		location, _ := result.Evidence["location"].(string)
+		hashes[services.EvidenceHash("redirect", input.URL, location, param, result.CWE)] = true
+	}
+	if len(destinations) != 2 {
+		t.Fatalf("payloads redirected to %v, want two destinations", destinations)
+	}
+	if len(hashes) != 1 {
+		t.Errorf("two payloads on one parameter gave %d fingerprints", len(hashes))
+	}
+}
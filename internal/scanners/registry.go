@@ -0,0 +1,86 @@
+package scanners
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kokuroshesh/bugvay/internal/httpclient"
+)
+
+// Factory builds a scanner bound to the worker's HTTP client
+type Factory func(client *httpclient.Scanner) Scanner
+
+// Registration describes a scanner module to the queue, worker and API
+type Registration struct {
+	Name     string        // scanner name used in API requests, e.g. "xss"
+	TaskType string        // Asynq task type, defaults to "scan:<name>"
+	Queue    string        // Asynq queue, defaults to "default"
+	CWE      int           // primary CWE reported by the scanner
+	Timeout  time.Duration // per-task timeout, defaults to 5 minutes
+	New      Factory
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Registration{}
+)
+
+// Register makes a scanner available by name. It is meant to be called from
+// the scanner package's init function and panics on invalid or duplicate
+// registrations, like database/sql.Register.
+func Register(r Registration) {
+	if r.Name == "" || r.New == nil {
+		panic("scanners: Register requires a name and a factory")
+	}
+	if r.TaskType == "" {
+		r.TaskType = "scan:" + r.Name
+	}
+	if r.Queue == "" {
+		r.Queue = "default"
+	}
+	if r.Timeout == 0 {
+		r.Timeout = 5 * time.Minute
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, dup := registry[r.Name]; dup {
+		panic(fmt.Sprintf("scanners: Register called twice for %q", r.Name))
+	}
+	registry[r.Name] = r
+}
+
+// Lookup returns the registration for a scanner name
+func Lookup(name string) (Registration, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	r, ok := registry[name]
+	return r, ok
+}
+
+// All returns every registered scanner, sorted by name
+func All() []Registration {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	regs := make([]Registration, 0, len(registry))
+	for _, r := range registry {
+		regs = append(regs, r)
+	}
+	sort.Slice(regs, func(i, j int) bool { return regs[i].Name < regs[j].Name })
+	return regs
+}
+
+// Names returns the names of all registered scanners, sorted
+func Names() []string {
+	regs := All()
+	names := make([]string, len(regs))
+	for i, r := range regs {
+		names[i] = r.Name
+	}
+	return names
+}
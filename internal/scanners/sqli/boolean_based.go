@@ -0,0 +1,68 @@
+package sqli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kokuroshesh/bugvay/internal/scanners"
+)
+
+// testBooleanBased injects a true and a false condition. The parameter is
+// injectable when the true condition renders the original page and the
+// false condition does not, consistently across a repeated round.
+func (s *SQLiScanner) testBooleanBased(ctx context.Context, point scanners.InsertionPoint, baseline *scanners.Baseline) *scanners.ScanResult {
+	// Pages that change between identical requests make every differential meaningless
+	if !baseline.Stable {
+		return nil
+	}
+
+	for _, pair := range booleanPayloads {
+		truePayload, falsePayload := point.Original+pair.True, point.Original+pair.False
+
+		trueResp, falseResp, ok := s.booleanRound(ctx, point, truePayload, falsePayload, baseline)
+		if !ok {
+			continue
+		}
+
+		// Confirm to rule out pages that change on every request
+		if _, _, ok := s.booleanRound(ctx, point, truePayload, falsePayload, baseline); !ok {
+			continue
+		}
+
+		return newResult(trueResp, 0.8, map[string]interface{}{
+			"param":           point.Name,
+			"location":        point.Location,
+			"payload":         truePayload,
+			"payload_true":    truePayload,
+			"payload_false":   falsePayload,
+			"url":             trueResp.url,
+			"technique":       "boolean-based",
+			"dbms":            "unknown",
+			"baseline_status": baseline.Status,
+			"baseline_length": baseline.Length,
+			"true_status":     trueResp.status,
+			"true_length":     len(trueResp.body),
+			"false_status":    falseResp.status,
+			"false_length":    len(falseResp.body),
+		}, fmt.Sprintf("Boolean condition changes response:\nTrue:  %s (status %d, %d bytes)\nFalse: %s (status %d, %d bytes)\nBaseline: status %d, %d bytes",
+			trueResp.url, trueResp.status, len(trueResp.body),
+			falseResp.url, falseResp.status, len(falseResp.body),
+			baseline.Status, baseline.Length))
+	}
+
+	return nil
+}
+
+func (s *SQLiScanner) booleanRound(ctx context.Context, point scanners.InsertionPoint, truePayload, falsePayload string, baseline *scanners.Baseline) (*response, *response, bool) {
+	trueResp, err := s.fetch(ctx, point, truePayload)
+	if err != nil || trueResp.differs(baseline, truePayload) {
+		return nil, nil, false
+	}
+
+	falseResp, err := s.fetch(ctx, point, falsePayload)
+	if err != nil || !falseResp.differs(baseline, falsePayload) {
+		return nil, nil, false
+	}
+
+	return trueResp, falseResp, true
+}
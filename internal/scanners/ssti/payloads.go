@@ -0,0 +1,149 @@
+package ssti
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/kokuroshesh/bugvay/internal/scanners"
+)
+
+// syntax is one expression delimiter family. Its probe multiplies two
+// random operands; finding the product means the template evaluated it.
+type syntax struct {
+	name    string
+	probe   func(a, b int) string
+	render  func(a, b int) string // evaluated probe, the product when nil
+	engines []engineCheck         // follow-up probes, most specific first
+}
+
+// engineCheck identifies one engine by an expression only it evaluates
+// to want. s is a random canary, n a random number.
+type engineCheck struct {
+	engine string
+	probe  func(s string, n int) (payload, want string)
+}
+
+var syntaxes = []syntax{
+	{
+		name:  "{{ }}",
+		probe: func(a, b int) string { return fmt.Sprintf("{{%d*%d}}", a, b) },
+		engines: []engineCheck{
+			// Twig ranges do not parse in Jinja2 or Nunjucks
+			{"twig", func(s string, n int) (string, string) {
+				return fmt.Sprintf("{{(%d..%d)|join('%s')}}", n, n+1, s), fmt.Sprintf("%d%s%d", n, s, n+1)
+			}},
+			// Python repeats strings; Tornado has no filters
+			{"jinja2", func(s string, n int) (string, string) {
+				return fmt.Sprintf("{{(3*'%s')|upper}}", s), strings.Repeat(strings.ToUpper(s), 3)
+			}},
+			{"tornado", func(s string, n int) (string, string) {
+				return fmt.Sprintf("{{3*'%s'}}", s), strings.Repeat(s, 3)
+			}},
+			// JavaScript methods are callable from Nunjucks
+			{"nunjucks", func(s string, n int) (string, string) {
+				return fmt.Sprintf("{{'%s'.toUpperCase()}}", s), strings.ToUpper(s)
+			}},
+		},
+	},
+	{
+		name: "{{ print }}",
+		// Go templates have no arithmetic; print concatenates string operands
+		probe:  func(a, b int) string { return fmt.Sprintf(`{{print "%d" "%d"}}`, a, b) },
+		render: func(a, b int) string { return strconv.Itoa(a) + strconv.Itoa(b) },
+		engines: []engineCheck{
+			{"go", func(s string, n int) (string, string) {
+				return fmt.Sprintf(`{{printf "%%s%%d" "%s" %d}}`, s, n), s + strconv.Itoa(n)
+			}},
+		},
+	},
+	{
+		name:  "${ }",
+		probe: func(a, b int) string { return fmt.Sprintf("${%d*%d}", a, b) },
+		engines: []engineCheck{
+			{"freemarker", func(s string, n int) (string, string) {
+				return fmt.Sprintf("${'%s'?upper_case}", s), strings.ToUpper(s)
+			}},
+			{"mako", func(s string, n int) (string, string) {
+				return fmt.Sprintf("${3*'%s'}", s), strings.Repeat(s, 3)
+			}},
+			// typeof only exists in JavaScript (template literals, lodash)
+			{"javascript", func(s string, n int) (string, string) {
+				return fmt.Sprintf("${typeof ''+'%s'}", s), "string" + s
+			}},
+			// Java EL, SpEL and Thymeleaf call Java methods
+			{"java-el", func(s string, n int) (string, string) {
+				return fmt.Sprintf("${'%s'.toUpperCase()}", s), strings.ToUpper(s)
+			}},
+		},
+	},
+	{
+		name:  "<%= %>",
+		probe: func(a, b int) string { return fmt.Sprintf("<%%= %d*%d %%>", a, b) },
+		engines: []engineCheck{
+			{"erb", func(s string, n int) (string, string) {
+				return fmt.Sprintf("<%%= '%s'.upcase %%>", s), strings.ToUpper(s)
+			}},
+			{"ejs", func(s string, n int) (string, string) {
+				return fmt.Sprintf("<%%= '%s'.toUpperCase() %%>", s), strings.ToUpper(s)
+			}},
+		},
+	},
+	{
+		name:  "#{ }",
+		probe: func(a, b int) string { return fmt.Sprintf("#{%d*%d}", a, b) },
+		engines: []engineCheck{
+			// Ruby interpolation in Slim and Haml
+			{"slim/haml", func(s string, n int) (string, string) {
+				return fmt.Sprintf("#{'%s'.upcase}", s), strings.ToUpper(s)
+			}},
+			{"pug", func(s string, n int) (string, string) {
+				return fmt.Sprintf("#{'%s'.toUpperCase()}", s), strings.ToUpper(s)
+			}},
+		},
+	},
+	{
+		name:  "{ }",
+		probe: func(a, b int) string { return fmt.Sprintf("{%d*%d}", a, b) },
+		engines: []engineCheck{
+			{"smarty", func(s string, n int) (string, string) {
+				return fmt.Sprintf("{'%s'|upper}", s), strings.ToUpper(s)
+			}},
+		},
+	},
+	{
+		name:  "@( )",
+		probe: func(a, b int) string { return fmt.Sprintf("@(%d*%d)", a, b) },
+		engines: []engineCheck{
+			{"razor", func(s string, n int) (string, string) {
+				return fmt.Sprintf(`@("%s".ToUpper())`, s), strings.ToUpper(s)
+			}},
+		},
+	},
+}
+
+// expected is what a syntax's probe renders to when evaluated
+func (syn syntax) expected(a, b int) string {
+	if syn.render != nil {
+		return syn.render(a, b)
+	}
+	return strconv.Itoa(a * b)
+}
+
+// engineSeverity is critical for engines where template injection is a
+// known path to code execution
+func engineSeverity(engine string) string {
+	switch engine {
+	case "":
+		return "high"
+	case "go":
+		return "medium" // no code execution without dangerous data in scope
+	}
+	return "critical"
+}
+
+// operands returns two random four-digit numbers, so a product in the
+// response cannot be a coincidence or a cached earlier probe
+func operands() (int, int) {
+	return scanners.RandInt(1000, 9999), scanners.RandInt(1000, 9999)
+}
@@ -0,0 +1,163 @@
+package ssti
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kokuroshesh/bugvay/internal/httpclient"
+	"github.com/kokuroshesh/bugvay/internal/scanners"
+)
+
+type SSTIScanner struct {
+	client *httpclient.Scanner
+}
+
+func init() {
+	scanners.Register(scanners.Registration{
+		Name:    "ssti",
+		CWE:     1336,
+		Timeout: 5 * time.Minute,
+		New:     func(c *httpclient.Scanner) scanners.Scanner { return New(c) },
+	})
+}
+
+func New(client *httpclient.Scanner) *SSTIScanner {
+	return &SSTIScanner{client: client}
+}
+
+func (s *SSTIScanner) Name() string {
+	return "ssti"
+}
+
+// evaluation is an arithmetic probe the template rendered
+type evaluation struct {
+	syntax  syntax
+	payload string
+	want    string
+	resp    *httpclient.Response
+}
+
+// Scan sends an arithmetic probe per template syntax to every parameter.
+// An evaluated probe is repeated with fresh operands, then engine-specific
+// follow-ups name the engine.
+func (s *SSTIScanner) Scan(ctx context.Context, input *scanners.ScanInput) (*scanners.ScanResult, error) {
+	points, err := input.InsertionPoints(scanners.InQuery, scanners.InForm, scanners.InJSON, scanners.InCookie)
+	if err != nil {
+		return nil, err
+	}
+	if len(points) == 0 {
+		return &scanners.ScanResult{Vulnerable: false}, nil
+	}
+
+	baseline, err := scanners.NewBaseline(ctx, s.client, input)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, point := range points {
+		// Several syntaxes can evaluate in one engine ("${{7*7}}" renders
+		// "$49" in Jinja2), so every evaluated family is fingerprinted
+		var evaluated []*evaluation
+		for _, syn := range syntaxes {
+			if ev := s.evaluates(ctx, point, baseline, syn); ev != nil {
+				evaluated = append(evaluated, ev)
+			}
+		}
+		if len(evaluated) > 0 {
+			return s.fingerprint(ctx, point, baseline, evaluated), nil
+		}
+
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+	}
+
+	return &scanners.ScanResult{Vulnerable: false}, nil
+}
+
+// evaluates sends syn's probe twice with different operands. Both results
+// must appear in the response and not in the baseline.
+func (s *SSTIScanner) evaluates(ctx context.Context, point scanners.InsertionPoint, baseline *scanners.Baseline, syn syntax) *evaluation {
+	var ev *evaluation
+	for i := 0; i < 2; i++ {
+		a, b := operands()
+		payload, want := syn.probe(a, b), syn.expected(a, b)
+
+		resp, err := s.fetch(ctx, point, payload)
+		if err != nil || !rendered(resp, baseline, want) {
+			return nil
+		}
+		if ev == nil {
+			ev = &evaluation{syntax: syn, payload: payload, want: want, resp: resp}
+		}
+	}
+	return ev
+}
+
+// fingerprint runs the engine checks of every evaluated syntax and reports
+// the first engine that matches
+func (s *SSTIScanner) fingerprint(ctx context.Context, point scanners.InsertionPoint, baseline *scanners.Baseline, evaluated []*evaluation) *scanners.ScanResult {
+	for _, ev := range evaluated {
+		for _, check := range ev.syntax.engines {
+			payload, want := check.probe(scanners.Canary(), scanners.RandInt(1000, 9999))
+			resp, err := s.fetch(ctx, point, payload)
+			if err != nil || !rendered(resp, baseline, want) {
+				continue
+			}
+
+			result := newResult(point, ev, check.engine, 0.95)
+			result.Evidence["engine_payload"] = payload
+			result.Evidence["engine_output"] = want
+			result.Proof += fmt.Sprintf("\n\nEngine probe: %s\nRendered: %s", payload, want)
+			return result
+		}
+	}
+
+	// Evaluated but unidentified: still a finding
+	return newResult(point, evaluated[0], "", 0.85)
+}
+
+// fetch sends the endpoint's request with payload injected at point.
+// Engines that fail to parse a probe answer 500 with the template error,
+// which the caller still reads, so 5xx responses are not retried.
+func (s *SSTIScanner) fetch(ctx context.Context, point scanners.InsertionPoint, payload string) (*httpclient.Response, error) {
+	req, err := point.Request(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	return s.client.DoRequest(httpclient.WithPayload(ctx, payload), req, httpclient.ServerErrorsAsSignal())
+}
+
+// rendered reports whether want is new in the response
+func rendered(resp *httpclient.Response, baseline *scanners.Baseline, want string) bool {
+	return strings.Contains(string(resp.Body), want) && !strings.Contains(baseline.Body, want)
+}
+
+func newResult(point scanners.InsertionPoint, ev *evaluation, engine string, confidence float64) *scanners.ScanResult {
+	reported := engine
+	if reported == "" {
+		reported = "unknown"
+	}
+
+	return &scanners.ScanResult{
+		Vulnerable: true,
+		Severity:   engineSeverity(engine),
+		CWE:        1336,
+		Evidence: map[string]interface{}{
+			"param":    point.Name,
+			"location": point.Location,
+			"payload":  ev.payload,
+			"url":      ev.resp.URL,
+			"syntax":   ev.syntax.name,
+			"engine":   reported,
+			"rendered": ev.want,
+			"status":   ev.resp.StatusCode,
+		},
+		Proof: fmt.Sprintf("Template expression evaluated server-side:\nURL: %s\nParam: %s\nPayload: %s\nRendered: %s\nEngine: %s",
+			ev.resp.URL, point.Name, ev.payload, ev.want, reported),
+		Confidence: confidence,
+		Response:   ev.resp,
+	}
+}
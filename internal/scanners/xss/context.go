@@ -0,0 +1,281 @@
+package xss
+
+import (
+	"bytes"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Reflection contexts a canary can land in
+const (
+	ctxElement          = "element"
+	ctxAttributeQuoted  = "attribute_quoted"
+	ctxAttributeUnquote = "attribute_unquoted"
+	ctxURLAttribute     = "url_attribute"
+	ctxScriptString     = "script_string"
+	ctxScript           = "script"
+	ctxComment          = "comment"
+)
+
+// reflection is one place the canary was found in the response
+type reflection struct {
+	Context string
+	Quote   byte   // enclosing quote for quoted attributes and script strings
+	Tag     string // raw text element (textarea, title, ...) that must be closed first
+	Attr    string // attribute name for attribute contexts
+}
+
+func (r reflection) key() string {
+	return r.Context + "|" + string(r.Quote) + "|" + r.Tag
+}
+
+// breakout lists the characters a payload needs to leave the context
+func (r reflection) breakout() string {
+	switch r.Context {
+	case ctxElement, ctxAttributeUnquote, ctxComment:
+		return "<>"
+	case ctxAttributeQuoted:
+		return string(r.Quote)
+	case ctxURLAttribute:
+		return ":()"
+	case ctxScriptString:
+		return string(r.Quote) + ";"
+	case ctxScript:
+		return ";()"
+	}
+	return ""
+}
+
+// urlAttrs are attributes whose value is navigated to or loaded as a URL
+var urlAttrs = map[string]bool{
+	"href":       true,
+	"src":        true,
+	"action":     true,
+	"formaction": true,
+	"data":       true,
+	"poster":     true,
+	"background": true,
+	"xlink:href": true,
+}
+
+// rawTextTags hold unparsed text, so markup inside them is inert until the
+// element is closed. style is left out: CSS contexts are not tested.
+var rawTextTags = map[string]bool{
+	"script":    true,
+	"textarea":  true,
+	"title":     true,
+	"xmp":       true,
+	"iframe":    true,
+	"noembed":   true,
+	"noframes":  true,
+	"noscript":  true,
+	"plaintext": true,
+}
+
+// findReflections tokenizes body and classifies every place canary appears
+func findReflections(body []byte, canary string) []reflection {
+	var found []reflection
+	seen := make(map[string]bool)
+	add := func(r reflection) {
+		if !seen[r.key()] {
+			seen[r.key()] = true
+			found = append(found, r)
+		}
+	}
+
+	z := html.NewTokenizer(bytes.NewReader(body))
+	openTag := ""
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			return found
+		}
+		raw := string(z.Raw())
+
+		switch tt {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, _ := z.TagName()
+			openTag = ""
+			if tt == html.StartTagToken && (rawTextTags[string(name)] || string(name) == "style") {
+				openTag = string(name)
+			}
+			if strings.Contains(raw, canary) {
+				for _, r := range tagReflections(raw, canary) {
+					add(r)
+				}
+			}
+
+		case html.TextToken:
+			if !strings.Contains(raw, canary) {
+				continue
+			}
+			switch openTag {
+			case "style":
+			case "script":
+				for _, idx := range indexAll(raw, canary) {
+					switch q := scriptQuote(raw[:idx]); q {
+					case 0:
+						add(reflection{Context: ctxScript})
+					case '/':
+						// Inside a JS comment; only </script> escapes it
+						add(reflection{Context: ctxElement, Tag: "script"})
+					default:
+						add(reflection{Context: ctxScriptString, Quote: q})
+					}
+				}
+			case "":
+				add(reflection{Context: ctxElement})
+			default:
+				add(reflection{Context: ctxElement, Tag: openTag})
+			}
+
+		case html.CommentToken:
+			if strings.Contains(raw, canary) {
+				add(reflection{Context: ctxComment})
+			}
+
+		default:
+			openTag = ""
+		}
+	}
+}
+
+// tagReflections classifies canary occurrences inside a raw start tag
+func tagReflections(raw, canary string) []reflection {
+	var out []reflection
+	for _, a := range parseAttrs(raw) {
+		if strings.Contains(raw[a.nameStart:a.nameEnd], canary) {
+			out = append(out, reflection{Context: ctxAttributeUnquote, Attr: a.name})
+			continue
+		}
+
+		value := raw[a.start:a.end]
+		if !strings.Contains(value, canary) {
+			continue
+		}
+		if urlAttrs[a.name] && strings.HasPrefix(strings.TrimSpace(value), canary) {
+			out = append(out, reflection{Context: ctxURLAttribute, Quote: a.quote, Attr: a.name})
+		}
+		if a.quote != 0 {
+			out = append(out, reflection{Context: ctxAttributeQuoted, Quote: a.quote, Attr: a.name})
+		} else {
+			out = append(out, reflection{Context: ctxAttributeUnquote, Attr: a.name})
+		}
+	}
+	return out
+}
+
+// attrSpan locates an attribute inside a raw tag. The tokenizer unescapes
+// values and drops the quoting, which is exactly what decides the context.
+type attrSpan struct {
+	name               string
+	nameStart, nameEnd int
+	start, end         int // value, excluding quotes
+	quote              byte
+}
+
+func parseAttrs(raw string) []attrSpan {
+	const sep = " \t\n\r\f/>"
+
+	i := 1
+	for i < len(raw) && !strings.ContainsRune(sep, rune(raw[i])) {
+		i++
+	}
+
+	var attrs []attrSpan
+	for i < len(raw) {
+		for i < len(raw) && (isSpace(raw[i]) || raw[i] == '/') {
+			i++
+		}
+		if i >= len(raw) || raw[i] == '>' {
+			break
+		}
+
+		a := attrSpan{nameStart: i}
+		for i < len(raw) && (i == a.nameStart || !strings.ContainsRune(sep+"=", rune(raw[i]))) {
+			i++
+		}
+		a.nameEnd = i
+		a.name = strings.ToLower(raw[a.nameStart:a.nameEnd])
+		a.start, a.end = i, i
+
+		j := i
+		for j < len(raw) && isSpace(raw[j]) {
+			j++
+		}
+		if j < len(raw) && raw[j] == '=' {
+			j++
+			for j < len(raw) && isSpace(raw[j]) {
+				j++
+			}
+			if j < len(raw) && (raw[j] == '"' || raw[j] == '\'') {
+				a.quote = raw[j]
+				a.start = j + 1
+				a.end = len(raw)
+				if k := strings.IndexByte(raw[a.start:], a.quote); k >= 0 {
+					a.end = a.start + k
+				}
+				i = a.end + 1
+			} else {
+				a.start = j
+				for j < len(raw) && !isSpace(raw[j]) && raw[j] != '>' {
+					j++
+				}
+				a.end = j
+				i = j
+			}
+		}
+		attrs = append(attrs, a)
+	}
+	return attrs
+}
+
+// scriptQuote returns the string delimiter open at the end of src, '/' when
+// src ends inside a comment, or 0 when it ends in code. Regex literals are
+// not recognised.
+func scriptQuote(src string) byte {
+	var q byte
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case q != 0:
+			if c == '\\' {
+				i++
+			} else if c == q {
+				q = 0
+			}
+		case c == '"' || c == '\'' || c == '`':
+			q = c
+		case c == '/' && i+1 < len(src) && src[i+1] == '/':
+			k := strings.IndexByte(src[i:], '\n')
+			if k < 0 {
+				return '/'
+			}
+			i += k
+		case c == '/' && i+1 < len(src) && src[i+1] == '*':
+			k := strings.Index(src[i+2:], "*/")
+			if k < 0 {
+				return '/'
+			}
+			i += k + 3
+		}
+	}
+	return q
+}
+
+func indexAll(s, sub string) []int {
+	var out []int
+	for off := 0; ; {
+		i := strings.Index(s[off:], sub)
+		if i < 0 {
+			return out
+		}
+		out = append(out, off+i)
+		off += i + len(sub)
+	}
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
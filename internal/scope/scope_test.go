@@ -0,0 +1,181 @@
+package scope
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"testing"
+)
+
+func mustScope(t *testing.T, rules ...Rule) *Scope {
+	t.Helper()
+	s, err := New(1, rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestCheck(t *testing.T) {
+	s := mustScope(t,
+		Rule{InScope, TypeHost, "*.example.com"},
+		Rule{InScope, TypeHost, "example.com"},
+		Rule{OutOfScope, TypeHost, "billing.example.com"},
+		Rule{OutOfScope, TypePath, "/admin"},
+		Rule{OutOfScope, TypePath, "/internal/"},
+	)
+
+	tests := []struct {
+		url string
+		ok  bool
+	}{
+		{"https://example.com/", true},
+		{"https://www.example.com/search?q=1", true},
+		{"https://example.com", true},
+		{"ftp://example.com/", false},
+		{"https://other.com/", false},
+		{"https://example.com.other.com/", false},
+		{"https://billing.example.com/", false},
+		{"https://BILLING.example.com./", false},
+
+		// Path rules compare whole segments of the normalised path
+		{"https://example.com/admin", false},
+		{"https://example.com/admin/users", false},
+		{"https://example.com/administrator", true},
+		{"https://example.com/Admin", false},
+		{"https://example.com/x/../admin", false},
+		{"https://example.com/%61dmin", false},
+		{"https://example.com//admin", false},
+		{"https://example.com/./admin/", false},
+		{"https://example.com/x\\..\\admin", false},
+		{"https://example.com/internal", false},
+		{"https://example.com/internals", true},
+	}
+	for _, tt := range tests {
+		u, err := url.Parse(tt.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = s.Check(context.Background(), u)
+		if tt.ok && err != nil {
+			t.Errorf("Check(%s) = %v, want in scope", tt.url, err)
+		}
+		if !tt.ok && !errors.Is(err, ErrOutOfScope) {
+			t.Errorf("Check(%s) = %v, want ErrOutOfScope", tt.url, err)
+		}
+	}
+}
+
+// Range rules make Check resolve the host; IP literals need no lookup
+func TestCheckCIDR(t *testing.T) {
+	s := mustScope(t,
+		Rule{InScope, TypeCIDR, "10.0.0.0/8"},
+		Rule{OutOfScope, TypeCIDR, "10.1.0.0/16"},
+	)
+
+	tests := []struct {
+		host string
+		ok   bool
+	}{
+		{"10.2.3.4", true},
+		{"10.1.3.4", false},
+		{"192.168.1.1", false},
+		{"[::1]", false},
+	}
+	for _, tt := range tests {
+		u := &url.URL{Scheme: "http", Host: tt.host, Path: "/"}
+		if err := s.Check(context.Background(), u); (err == nil) != tt.ok {
+			t.Errorf("Check(%s) = %v, want ok=%v", tt.host, err, tt.ok)
+		}
+	}
+}
+
+func TestCheckInPaths(t *testing.T) {
+	s := mustScope(t,
+		Rule{InScope, TypeHost, "example.com"},
+		Rule{InScope, TypePath, "/api"},
+	)
+
+	tests := []struct {
+		path string
+		ok   bool
+	}{
+		{"/api", true},
+		{"/api/v1/users", true},
+		{"/apiv2", false},
+		{"/api/../admin", false},
+		{"/", false},
+	}
+	for _, tt := range tests {
+		u := &url.URL{Scheme: "https", Host: "example.com", Path: tt.path}
+		if err := s.Check(context.Background(), u); (err == nil) != tt.ok {
+			t.Errorf("Check(%s) = %v, want ok=%v", tt.path, err, tt.ok)
+		}
+	}
+}
+
+func TestCheckAddr(t *testing.T) {
+	s := mustScope(t,
+		Rule{InScope, TypeHost, "*.example.com"},
+		Rule{InScope, TypeCIDR, "10.0.0.0/8"},
+		Rule{OutOfScope, TypeCIDR, "10.1.0.0/16"},
+		Rule{OutOfScope, TypeCIDR, "169.254.0.0/16"},
+	)
+
+	tests := []struct {
+		host, ip string
+		ok       bool
+	}{
+		{"www.example.com", "93.184.216.34", true},
+		{"www.example.com", "169.254.169.254", false}, // rebound to metadata
+		{"www.example.com", "10.1.0.5", false},
+		{"ranged.test", "10.2.0.5", true},
+		{"ranged.test", "127.0.0.1", false},
+		{"10.2.0.5", "10.2.0.5", true},
+	}
+	for _, tt := range tests {
+		err := s.CheckAddr(tt.host, net.ParseIP(tt.ip))
+		if tt.ok && err != nil {
+			t.Errorf("CheckAddr(%s, %s) = %v, want allowed", tt.host, tt.ip, err)
+		}
+		if !tt.ok && !errors.Is(err, ErrOutOfScope) {
+			t.Errorf("CheckAddr(%s, %s) = %v, want ErrOutOfScope", tt.host, tt.ip, err)
+		}
+	}
+}
+
+func TestMatchHost(t *testing.T) {
+	tests := []struct {
+		pattern, host string
+		want          bool
+	}{
+		{"example.com", "example.com", true},
+		{"example.com", "www.example.com", false},
+		{"*.example.com", "www.example.com", true},
+		{"*.example.com", "example.com", false},
+		{"*.example.com", "a.b.example.com", true},
+		{"*.example.com", "example.com.evil.com", false},
+		{"api-?.example.com", "api-1.example.com", true},
+	}
+	for _, tt := range tests {
+		if got := matchHost(tt.pattern, tt.host); got != tt.want {
+			t.Errorf("matchHost(%q, %q) = %v, want %v", tt.pattern, tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	bad := []Rule{
+		{"maybe", TypeHost, "example.com"},
+		{InScope, TypeHost, ""},
+		{InScope, TypePath, "admin"},
+		{InScope, TypeCIDR, "10.0.0.0/33"},
+		{InScope, "port", "443"},
+	}
+	for _, r := range bad {
+		if err := r.Validate(); !errors.Is(err, ErrInvalidRule) {
+			t.Errorf("Validate(%+v) = %v, want ErrInvalidRule", r, err)
+		}
+	}
+}
@@ -0,0 +1,274 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kokuroshesh/bugvay/internal/database"
+)
+
+var (
+	// ErrAnalyticsUnavailable is returned when the API runs without ClickHouse
+	ErrAnalyticsUnavailable = errors.New("analytics unavailable: ClickHouse not connected")
+	ErrInvalidBucket        = errors.New("invalid bucket (must be: hour, day, week)")
+)
+
+type AnalyticsService struct {
+	ch *database.ClickHouseDB
+}
+
+type AnalyticsFilter struct {
+	ProgramID int
+	Scanner   string
+	From      time.Time
+	To        time.Time
+	Bucket    string // hour, day, week
+	Limit     int
+}
+
+type VulnRatePoint struct {
+	Bucket     time.Time `json:"bucket"`
+	Scanner    string    `json:"scanner"`
+	VulnCount  uint64    `json:"vuln_count"`
+	TotalScans uint64    `json:"total_scans"`
+	Rate       float64   `json:"rate"`
+}
+
+type ResponseTimePoint struct {
+	Bucket    time.Time `json:"bucket"`
+	ProgramID uint32    `json:"program_id"`
+	Requests  uint64    `json:"requests"`
+	AvgMs     float64   `json:"avg_ms"`
+	P95Ms     float64   `json:"p95_ms"`
+}
+
+type HostLatency struct {
+	Host     string  `json:"host"`
+	Requests uint64  `json:"requests"`
+	AvgMs    float64 `json:"avg_ms"`
+	P95Ms    float64 `json:"p95_ms"`
+	Errors   uint64  `json:"errors"`
+}
+
+type ParamCount struct {
+	Param     string    `json:"param"`
+	Findings  uint64    `json:"findings"`
+	Endpoints uint64    `json:"endpoints"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// bucketFuncs whitelists the time bucketing functions callers may pick
+var bucketFuncs = map[string]string{
+	"hour": "toStartOfHour",
+	"day":  "toStartOfDay",
+	"week": "toMonday",
+}
+
+func NewAnalyticsService(ch *database.ClickHouseDB) *AnalyticsService {
+	return &AnalyticsService{ch: ch}
+}
+
+// VulnerabilityRate returns findings per probe for each scanner over time
+func (s *AnalyticsService) VulnerabilityRate(ctx context.Context, f *AnalyticsFilter) ([]VulnRatePoint, error) {
+	if s.ch == nil {
+		return nil, ErrAnalyticsUnavailable
+	}
+
+	bucket, err := bucketFunc(f.Bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	where, args := f.where("hour")
+	query := fmt.Sprintf(`
+		SELECT toDateTime(%s(hour)) AS bucket, scanner,
+			sum(vuln_count) AS vulns, sum(total_scans) AS total
+		FROM scan_stats_hourly
+		WHERE %s
+		GROUP BY bucket, scanner
+		ORDER BY bucket, scanner
+	`, bucket, where)
+
+	rows, err := s.ch.Conn.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query vulnerability rate: %w", err)
+	}
+	defer rows.Close()
+
+	points := []VulnRatePoint{}
+	for rows.Next() {
+		var p VulnRatePoint
+		if err := rows.Scan(&p.Bucket, &p.Scanner, &p.VulnCount, &p.TotalScans); err != nil {
+			return nil, fmt.Errorf("scan row: %w", err)
+		}
+		if p.TotalScans > 0 {
+			p.Rate = float64(p.VulnCount) / float64(p.TotalScans)
+		}
+		points = append(points, p)
+	}
+
+	return points, rows.Err()
+}
+
+// ResponseTimes returns average and p95 response time per program over time
+func (s *AnalyticsService) ResponseTimes(ctx context.Context, f *AnalyticsFilter) ([]ResponseTimePoint, error) {
+	if s.ch == nil {
+		return nil, ErrAnalyticsUnavailable
+	}
+
+	bucket, err := bucketFunc(f.Bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	where, args := f.where("created_at")
+	query := fmt.Sprintf(`
+		SELECT toDateTime(%s(created_at)) AS bucket, program_id,
+			count() AS requests, avg(response_time_ms) AS avg_ms,
+			quantile(0.95)(response_time_ms) AS p95_ms
+		FROM scan_results
+		WHERE scan_type = 'probe' AND status_code > 0 AND %s
+		GROUP BY bucket, program_id
+		ORDER BY bucket, program_id
+	`, bucket, where)
+
+	rows, err := s.ch.Conn.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query response times: %w", err)
+	}
+	defer rows.Close()
+
+	points := []ResponseTimePoint{}
+	for rows.Next() {
+		var p ResponseTimePoint
+		if err := rows.Scan(&p.Bucket, &p.ProgramID, &p.Requests, &p.AvgMs, &p.P95Ms); err != nil {
+			return nil, fmt.Errorf("scan row: %w", err)
+		}
+		points = append(points, p)
+	}
+
+	return points, rows.Err()
+}
+
+// SlowestHosts ranks target hosts by p95 response time
+func (s *AnalyticsService) SlowestHosts(ctx context.Context, f *AnalyticsFilter) ([]HostLatency, error) {
+	if s.ch == nil {
+		return nil, ErrAnalyticsUnavailable
+	}
+
+	where, args := f.where("created_at")
+	query := fmt.Sprintf(`
+		SELECT domain(url) AS host, count() AS requests,
+			avg(response_time_ms) AS avg_ms,
+			quantile(0.95)(response_time_ms) AS p95_ms,
+			countIf(status_code = 0 OR status_code >= 500) AS errors
+		FROM scan_results
+		WHERE scan_type = 'probe' AND %s
+		GROUP BY host
+		ORDER BY p95_ms DESC
+		LIMIT %d
+	`, where, f.limit())
+
+	rows, err := s.ch.Conn.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query slowest hosts: %w", err)
+	}
+	defer rows.Close()
+
+	hosts := []HostLatency{}
+	for rows.Next() {
+		var h HostLatency
+		if err := rows.Scan(&h.Host, &h.Requests, &h.AvgMs, &h.P95Ms, &h.Errors); err != nil {
+			return nil, fmt.Errorf("scan row: %w", err)
+		}
+		hosts = append(hosts, h)
+	}
+
+	return hosts, rows.Err()
+}
+
+// TopReflectedParams ranks parameters by confirmed findings, XSS by default
+func (s *AnalyticsService) TopReflectedParams(ctx context.Context, f *AnalyticsFilter) ([]ParamCount, error) {
+	if s.ch == nil {
+		return nil, ErrAnalyticsUnavailable
+	}
+
+	if f.Scanner == "" {
+		f.Scanner = "xss"
+	}
+
+	where, args := f.where("created_at")
+	query := fmt.Sprintf(`
+		SELECT JSONExtractString(evidence, 'param') AS param,
+			count() AS findings, uniqExact(endpoint_id) AS endpoints,
+			max(created_at) AS last_seen
+		FROM scan_results
+		WHERE vulnerable AND param != '' AND %s
+		GROUP BY param
+		ORDER BY findings DESC
+		LIMIT %d
+	`, where, f.limit())
+
+	rows, err := s.ch.Conn.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query reflected params: %w", err)
+	}
+	defer rows.Close()
+
+	params := []ParamCount{}
+	for rows.Next() {
+		var p ParamCount
+		if err := rows.Scan(&p.Param, &p.Findings, &p.Endpoints, &p.LastSeen); err != nil {
+			return nil, fmt.Errorf("scan row: %w", err)
+		}
+		params = append(params, p)
+	}
+
+	return params, rows.Err()
+}
+
+// where builds the shared time, program and scanner filter
+func (f *AnalyticsFilter) where(timeColumn string) (string, []interface{}) {
+	to := f.To
+	if to.IsZero() {
+		to = time.Now()
+	}
+	from := f.From
+	if from.IsZero() {
+		from = to.Add(-7 * 24 * time.Hour)
+	}
+
+	clause := timeColumn + " >= ? AND " + timeColumn + " < ?"
+	args := []interface{}{from, to}
+
+	if f.ProgramID > 0 {
+		clause += " AND program_id = ?"
+		args = append(args, uint32(f.ProgramID))
+	}
+	if f.Scanner != "" {
+		clause += " AND scanner = ?"
+		args = append(args, f.Scanner)
+	}
+
+	return clause, args
+}
+
+func (f *AnalyticsFilter) limit() int {
+	if f.Limit <= 0 || f.Limit > 1000 {
+		return 20
+	}
+	return f.Limit
+}
+
+func bucketFunc(bucket string) (string, error) {
+	if bucket == "" {
+		bucket = "hour"
+	}
+	fn, ok := bucketFuncs[bucket]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrInvalidBucket, bucket)
+	}
+	return fn, nil
+}
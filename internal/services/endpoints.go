@@ -4,8 +4,12 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"maps"
+	"net/http"
 	"net/url"
+	"regexp"
 	"sort"
 	"strings"
 	"time"
@@ -16,55 +20,107 @@ import (
 )
 
 type EndpointService struct {
-	pg *database.PostgresDB
-	ch *database.ClickHouseDB
-	q  *queue.Client
+	pg     *database.PostgresDB
+	ch     *database.ClickHouseDB
+	q      *queue.Client
+	scopes *ScopeService
 }
 
 type Endpoint struct {
-	ID           int       `json:"id"`
-	AssetID      int       `json:"asset_id"`
-	URL          string    `json:"url"`
-	CanonicalURL string    `json:"canonical_url"`
-	Hash         string    `json:"hash"`
-	Crawled      bool      `json:"crawled"`
-	DiscoveredBy []string  `json:"discovered_by"`
-	CreatedAt    time.Time `json:"created_at"`
+	ID           int               `json:"id"`
+	AssetID      int               `json:"asset_id"`
+	URL          string            `json:"url"`
+	Method       string            `json:"method"`
+	Headers      map[string]string `json:"headers,omitempty"`
+	Body         string            `json:"body,omitempty"`
+	CanonicalURL string            `json:"canonical_url"`
+	Hash         string            `json:"hash"`
+	Crawled      bool              `json:"crawled"`
+	DiscoveredBy []string          `json:"discovered_by"`
+	CreatedAt    time.Time         `json:"created_at"`
+}
+
+// EndpointRequest is the request an endpoint is scanned with. Only the URL
+// is required; a GET without extra headers or body is assumed otherwise.
+type EndpointRequest struct {
+	URL     string            `json:"url" binding:"required"`
+	Method  string            `json:"method"`
+	Headers map[string]string `json:"headers"`
+	Body    string            `json:"body"`
+}
+
+// ErrInvalidEndpoint is returned for endpoint requests that cannot be sent
+var ErrInvalidEndpoint = errors.New("invalid endpoint")
+
+var methodRe = regexp.MustCompile(`^[A-Z]+$`)
+
+const endpointColumns = "id, asset_id, url, method, headers, body, canonical_url, hash, crawled, discovered_by, created_at"
+
+func scanEndpoint(row pgx.Row) (*Endpoint, error) {
+	var e Endpoint
+	if err := row.Scan(&e.ID, &e.AssetID, &e.URL, &e.Method, &e.Headers, &e.Body,
+		&e.CanonicalURL, &e.Hash, &e.Crawled, &e.DiscoveredBy, &e.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &e, nil
 }
 
 func NewEndpointService(pg *database.PostgresDB, ch *database.ClickHouseDB, q *queue.Client) *EndpointService {
-	return &EndpointService{pg: pg, ch: ch, q: q}
+	return &EndpointService{pg: pg, ch: ch, q: q, scopes: NewScopeService(pg)}
 }
 
-func (s *EndpointService) CreateEndpoint(ctx context.Context, assetID int, rawURL, source string) (*Endpoint, error) {
-	canonical := CanonicalizeURL(rawURL)
-	hash := HashURL(canonical)
+func (s *EndpointService) CreateEndpoint(ctx context.Context, assetID int, req EndpointRequest, source string) (*Endpoint, error) {
+	req.Method = strings.ToUpper(req.Method)
+	if req.Method == "" {
+		req.Method = http.MethodGet
+	}
+	if !methodRe.MatchString(req.Method) {
+		return nil, fmt.Errorf("%w: method %q", ErrInvalidEndpoint, req.Method)
+	}
+	if req.Headers == nil {
+		req.Headers = map[string]string{}
+	}
 
-	var endpoint Endpoint
+	// Never store what we are not allowed to test
+	u, err := url.Parse(req.URL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: parse url: %v", ErrInvalidEndpoint, err)
+	}
+	sc, err := s.scopes.LoadForAsset(ctx, assetID)
+	if err != nil {
+		return nil, fmt.Errorf("load scope: %w", err)
+	}
+	if err := sc.Check(ctx, u); err != nil {
+		return nil, err
+	}
+
+	canonical := CanonicalizeURL(req.URL)
+	hash := HashURL(endpointKey(canonical, req.Method, req.Body))
 
 	// Check if endpoint already exists
-	err := s.pg.Pool.QueryRow(ctx, `
-		SELECT id, asset_id, url, canonical_url, hash, crawled, discovered_by, created_at
-		FROM endpoints WHERE hash = $1
-	`, hash).Scan(
-		&endpoint.ID, &endpoint.AssetID, &endpoint.URL, &endpoint.CanonicalURL,
-		&endpoint.Hash, &endpoint.Crawled, &endpoint.DiscoveredBy, &endpoint.CreatedAt,
-	)
+	endpoint, err := scanEndpoint(s.pg.Pool.QueryRow(ctx, `
+		SELECT `+endpointColumns+` FROM endpoints WHERE hash = $1
+	`, hash))
 
 	if err == pgx.ErrNoRows {
 		// Create new endpoint
-		err = s.pg.Pool.QueryRow(ctx, `
-			INSERT INTO endpoints (asset_id, url, canonical_url, hash, discovered_by)
-			VALUES ($1, $2, $3, $4, $5)
-			RETURNING id, asset_id, url, canonical_url, hash, crawled, discovered_by, created_at
-		`, assetID, rawURL, canonical, hash, []string{source}).Scan(
-			&endpoint.ID, &endpoint.AssetID, &endpoint.URL, &endpoint.CanonicalURL,
-			&endpoint.Hash, &endpoint.Crawled, &endpoint.DiscoveredBy, &endpoint.CreatedAt,
-		)
+		endpoint, err = scanEndpoint(s.pg.Pool.QueryRow(ctx, `
+			INSERT INTO endpoints (asset_id, url, method, headers, body, canonical_url, hash, discovered_by)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			RETURNING `+endpointColumns,
+			assetID, req.URL, req.Method, req.Headers, req.Body, canonical, hash, []string{source}))
 		if err != nil {
 			return nil, fmt.Errorf("insert endpoint: %w", err)
 		}
 	} else if err == nil {
+		if replaceHeaders(endpoint.Headers, req.Headers) {
+			_, err = s.pg.Pool.Exec(ctx, `UPDATE endpoints SET headers = $1 WHERE id = $2`, req.Headers, endpoint.ID)
+			if err != nil {
+				return nil, fmt.Errorf("update headers: %w", err)
+			}
+			endpoint.Headers = req.Headers
+		}
+
 		// Endpoint exists, update discovered_by only if source not already present
 		if !contains(endpoint.DiscoveredBy, source) {
 			_, err = s.pg.Pool.Exec(ctx, `
@@ -81,15 +137,13 @@ func (s *EndpointService) CreateEndpoint(ctx context.Context, assetID int, rawUR
 		return nil, fmt.Errorf("check endpoint: %w", err)
 	}
 
-	return &endpoint, nil
+	return endpoint, nil
 }
 
 func (s *EndpointService) GetEndpoint(ctx context.Context, id int) (*Endpoint, error) {
-	var e Endpoint
-	err := s.pg.Pool.QueryRow(ctx, `
-		SELECT id, asset_id, url, canonical_url, hash, crawled, discovered_by, created_at
-		FROM endpoints WHERE id = $1
-	`, id).Scan(&e.ID, &e.AssetID, &e.URL, &e.CanonicalURL, &e.Hash, &e.Crawled, &e.DiscoveredBy, &e.CreatedAt)
+	e, err := scanEndpoint(s.pg.Pool.QueryRow(ctx, `
+		SELECT `+endpointColumns+` FROM endpoints WHERE id = $1
+	`, id))
 
 	if err == pgx.ErrNoRows {
 		return nil, fmt.Errorf("endpoint not found")
@@ -98,14 +152,11 @@ func (s *EndpointService) GetEndpoint(ctx context.Context, id int) (*Endpoint, e
 		return nil, fmt.Errorf("query endpoint: %w", err)
 	}
 
-	return &e, nil
+	return e, nil
 }
 
 func (s *EndpointService) ListEndpoints(ctx context.Context, assetID int, limit, offset int) ([]Endpoint, error) {
-	query := `
-		SELECT id, asset_id, url, canonical_url, hash, crawled, discovered_by, created_at
-		FROM endpoints
-	`
+	query := `SELECT ` + endpointColumns + ` FROM endpoints`
 	args := []interface{}{}
 
 	if assetID > 0 {
@@ -124,16 +175,34 @@ func (s *EndpointService) ListEndpoints(ctx context.Context, assetID int, limit,
 
 	var endpoints []Endpoint
 	for rows.Next() {
-		var e Endpoint
-		if err := rows.Scan(&e.ID, &e.AssetID, &e.URL, &e.CanonicalURL, &e.Hash, &e.Crawled, &e.DiscoveredBy, &e.CreatedAt); err != nil {
+		e, err := scanEndpoint(rows)
+		if err != nil {
 			return nil, fmt.Errorf("scan row: %w", err)
 		}
-		endpoints = append(endpoints, e)
+		endpoints = append(endpoints, *e)
 	}
 
 	return endpoints, nil
 }
 
+// replaceHeaders reports whether a re-imported endpoint's headers replace
+// the stored ones. Headers are not part of the endpoint key, so a new
+// import with e.g. fresh credentials updates them, while an import that
+// sends none (a plain URL list) keeps what is stored.
+func replaceHeaders(stored, incoming map[string]string) bool {
+	return len(incoming) > 0 && !maps.Equal(stored, incoming)
+}
+
+// endpointKey is what endpoints are deduplicated on. A plain GET keeps its
+// canonical URL as key, so endpoints stored before methods were keep their
+// hash; other requests to the same URL are distinct endpoints.
+func endpointKey(canonical, method, body string) string {
+	if method == http.MethodGet && body == "" {
+		return canonical
+	}
+	return method + " " + canonical + "\n" + body
+}
+
 // CanonicalizeURL normalizes URLs for deduplication
 func CanonicalizeURL(rawURL string) string {
 	u, err := url.Parse(rawURL)
@@ -0,0 +1,41 @@
+package services
+
+import "testing"
+
+func TestEndpointKey(t *testing.T) {
+	const u = "https://example.com/login"
+	if got := endpointKey(u, "GET", ""); got != u {
+		t.Errorf("plain GET key = %q, want the canonical URL", got)
+	}
+	keys := map[string]bool{}
+	for _, k := range []string{
+		endpointKey(u, "GET", ""),
+		endpointKey(u, "POST", ""),
+		endpointKey(u, "POST", "user=a"),
+		endpointKey(u, "PUT", "user=a"),
+	} {
+		keys[k] = true
+	}
+	if len(keys) != 4 {
+		t.Errorf("keys collide: %v", keys)
+	}
+}
+
+func TestReplaceHeaders(t *testing.T) {
+	stored := map[string]string{"Authorization": "Bearer old"}
+	tests := []struct {
+		name     string
+		incoming map[string]string
+		want     bool
+	}{
+		{"plain re-import", map[string]string{}, false},
+		{"same headers", map[string]string{"Authorization": "Bearer old"}, false},
+		{"fresh credentials", map[string]string{"Authorization": "Bearer new"}, true},
+		{"added header", map[string]string{"Authorization": "Bearer old", "X-Tenant": "a"}, true},
+	}
+	for _, tt := range tests {
+		if got := replaceHeaders(stored, tt.incoming); got != tt.want {
+			t.Errorf("%s: replaceHeaders = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
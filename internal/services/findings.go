@@ -2,7 +2,13 @@ package services
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"log"
+	"net/url"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5"
@@ -15,41 +21,136 @@ type FindingService struct {
 }
 
 type Finding struct {
-	ID         int                    `json:"id"`
-	EndpointID int                    `json:"endpoint_id"`
-	Scanner    string                 `json:"scanner"`
-	Severity   string                 `json:"severity"`
-	CWE        int                    `json:"cwe,omitempty"`
-	Evidence   map[string]interface{} `json:"evidence"`
-	Proof      string                 `json:"proof"`
-	Status     string                 `json:"status"`
-	CreatedAt  time.Time              `json:"created_at"`
+	ID           int                    `json:"id"`
+	EndpointID   int                    `json:"endpoint_id"`
+	Scanner      string                 `json:"scanner"`
+	Severity     string                 `json:"severity"`
+	CWE          int                    `json:"cwe,omitempty"`
+	Evidence     map[string]interface{} `json:"evidence"`
+	Proof        string                 `json:"proof"`
+	Status       string                 `json:"status"`
+	EvidenceHash string                 `json:"evidence_hash,omitempty"`
+	Occurrences  int                    `json:"occurrences"`
+	CreatedAt    time.Time              `json:"created_at"`
+	LastSeen     time.Time              `json:"last_seen"`
 }
 
+// Findings proven out-of-band are stored as pending until their callback
+// arrives, then confirmed
+const (
+	FindingPending   = "pending"
+	FindingConfirmed = "confirmed"
+)
+
 type TriageRequest struct {
 	Status        string `json:"status"`
 	FalsePositive bool   `json:"false_positive"`
 }
 
+const findingColumns = `id, endpoint_id, scanner, severity, COALESCE(cwe, 0), evidence, proof, status,
+	COALESCE(evidence_hash, ''), occurrences, created_at, last_seen`
+
+func (f *Finding) fields() []interface{} {
+	return []interface{}{&f.ID, &f.EndpointID, &f.Scanner, &f.Severity, &f.CWE, &f.Evidence, &f.Proof, &f.Status,
+		&f.EvidenceHash, &f.Occurrences, &f.CreatedAt, &f.LastSeen}
+}
+
 func NewFindingService(pg *database.PostgresDB, ch *database.ClickHouseDB) *FindingService {
 	return &FindingService{pg: pg, ch: ch}
 }
 
+// CreateFinding inserts a finding, or bumps last_seen and occurrences of
+// the existing finding with the same evidence fingerprint. Triage status of
+// an existing finding is left alone, except that a pending finding takes
+// the status of a detection that no longer needs confirmation. A pending
+// result never replaces the evidence of a settled finding.
 func (s *FindingService) CreateFinding(ctx context.Context, f *Finding) error {
-	_, err := s.pg.Pool.Exec(ctx, `
-		INSERT INTO findings (endpoint_id, scanner, severity, cwe, evidence, proof, status)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
-	`, f.EndpointID, f.Scanner, f.Severity, f.CWE, f.Evidence, f.Proof, f.Status)
+	if f.EvidenceHash == "" {
+		var endpointURL string
+		err := s.pg.Pool.QueryRow(ctx, "SELECT url FROM endpoints WHERE id = $1", f.EndpointID).Scan(&endpointURL)
+		if err != nil {
+			return fmt.Errorf("query endpoint: %w", err)
+		}
 
-	return err
+		param, _ := f.Evidence["param"].(string)
+		location, _ := f.Evidence["location"].(string)
+		f.EvidenceHash = EvidenceHash(f.Scanner, endpointURL, location, param, f.CWE)
+	}
+
+	err := s.pg.Pool.QueryRow(ctx, `
+		INSERT INTO findings (endpoint_id, scanner, severity, cwe, evidence, proof, status, evidence_hash)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (evidence_hash) WHERE evidence_hash IS NOT NULL DO UPDATE SET
+			last_seen = NOW(),
+			occurrences = findings.occurrences + 1,
+			evidence = CASE WHEN EXCLUDED.status = $9 AND findings.status <> $9 THEN findings.evidence ELSE EXCLUDED.evidence END,
+			proof = CASE WHEN EXCLUDED.status = $9 AND findings.status <> $9 THEN findings.proof ELSE EXCLUDED.proof END,
+			status = CASE WHEN findings.status = $9 THEN EXCLUDED.status ELSE findings.status END
+		RETURNING id, status, occurrences, created_at, last_seen
+	`, f.EndpointID, f.Scanner, f.Severity, f.CWE, f.Evidence, f.Proof, f.Status, f.EvidenceHash, FindingPending).Scan(
+		&f.ID, &f.Status, &f.Occurrences, &f.CreatedAt, &f.LastSeen,
+	)
+	if err != nil {
+		return fmt.Errorf("upsert finding: %w", err)
+	}
+
+	s.indexEvidence(ctx, f)
+	return nil
+}
+
+// indexEvidence mirrors the fingerprint into ClickHouse evidence_index.
+// ReplacingMergeTree(last_seen) keeps the newest row per fingerprint.
+func (s *FindingService) indexEvidence(ctx context.Context, f *Finding) {
+	if s.ch == nil {
+		return
+	}
+
+	err := s.ch.Conn.Exec(ctx, `
+		INSERT INTO evidence_index (evidence_hash, endpoint_id, scanner, first_seen, last_seen, occurrences)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, f.EvidenceHash, uint32(f.EndpointID), f.Scanner, f.CreatedAt, f.LastSeen, uint32(f.Occurrences))
+	if err != nil {
+		log.Printf("Failed to index evidence %s: %v", f.EvidenceHash, err)
+	}
+}
+
+// EvidenceHash fingerprints a vulnerability independent of payload and
+// parameter values: scanner, canonical endpoint (parameter names only),
+// insertion point location and name, and vulnerability class. The location
+// keeps a query parameter and a cookie of the same name apart. It is 32 hex
+// characters to fit ClickHouse evidence_index.evidence_hash.
+func EvidenceHash(scanner, endpointURL, location, param string, cwe int) string {
+	h := sha256.New()
+	for _, part := range []string{scanner, canonicalEndpoint(endpointURL), location, param, fmt.Sprintf("CWE-%d", cwe)} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))[:32]
+}
+
+// canonicalEndpoint reduces a URL to scheme, host, path and sorted
+// parameter names, so ?id=1 and ?id=2 are the same endpoint
+func canonicalEndpoint(rawURL string) string {
+	u, err := url.Parse(CanonicalizeURL(rawURL))
+	if err != nil {
+		return rawURL
+	}
+
+	names := make([]string, 0, len(u.Query()))
+	for k := range u.Query() {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	return strings.ToLower(u.Scheme+"://"+u.Host) + u.Path + "?" + strings.Join(names, "&")
 }
 
 func (s *FindingService) GetFinding(ctx context.Context, id int) (*Finding, error) {
 	var f Finding
 	err := s.pg.Pool.QueryRow(ctx, `
-		SELECT id, endpoint_id, scanner, severity, cwe, evidence, proof, status, created_at
+		SELECT `+findingColumns+`
 		FROM findings WHERE id = $1
-	`, id).Scan(&f.ID, &f.EndpointID, &f.Scanner, &f.Severity, &f.CWE, &f.Evidence, &f.Proof, &f.Status, &f.CreatedAt)
+	`, id).Scan(f.fields()...)
 
 	if err == pgx.ErrNoRows {
 		return nil, fmt.Errorf("finding not found")
@@ -63,7 +164,7 @@ func (s *FindingService) GetFinding(ctx context.Context, id int) (*Finding, erro
 
 func (s *FindingService) ListFindings(ctx context.Context, filters map[string]interface{}, limit, offset int) ([]Finding, error) {
 	query := `
-		SELECT id, endpoint_id, scanner, severity, COALESCE(cwe, 0), evidence, proof, status, created_at
+		SELECT ` + findingColumns + `
 		FROM findings
 		WHERE 1=1
 	`
@@ -94,7 +195,7 @@ func (s *FindingService) ListFindings(ctx context.Context, filters map[string]in
 	var findings []Finding
 	for rows.Next() {
 		var f Finding
-		if err := rows.Scan(&f.ID, &f.EndpointID, &f.Scanner, &f.Severity, &f.CWE, &f.Evidence, &f.Proof, &f.Status, &f.CreatedAt); err != nil {
+		if err := rows.Scan(f.fields()...); err != nil {
 			return nil, fmt.Errorf("scan row: %w", err)
 		}
 		findings = append(findings, f)
@@ -0,0 +1,32 @@
+package services
+
+import "testing"
+
+func TestEvidenceHash(t *testing.T) {
+	base := EvidenceHash("xss", "https://example.com/search?q=1&page=2", "query", "q", 79)
+	if len(base) != 32 {
+		t.Fatalf("len = %d, want 32", len(base))
+	}
+
+	tests := []struct {
+		name                       string
+		scanner, url, location, pa string
+		cwe                        int
+		same                       bool
+	}{
+		{"other values", "xss", "https://example.com/search?page=9&q=test", "query", "q", 79, true},
+		{"host case and fragment", "xss", "https://EXAMPLE.com/search?q=1&page=2#top", "query", "q", 79, true},
+		{"cookie of the same name", "xss", "https://example.com/search?q=1&page=2", "cookie", "q", 79, false},
+		{"body field of the same name", "xss", "https://example.com/search?q=1&page=2", "body_form", "q", 79, false},
+		{"other parameter", "xss", "https://example.com/search?q=1&page=2", "query", "page", 79, false},
+		{"other parameter set", "xss", "https://example.com/search?q=1", "query", "q", 79, false},
+		{"other scanner", "sqli", "https://example.com/search?q=1&page=2", "query", "q", 79, false},
+		{"other class", "xss", "https://example.com/search?q=1&page=2", "query", "q", 89, false},
+	}
+	for _, tt := range tests {
+		got := EvidenceHash(tt.scanner, tt.url, tt.location, tt.pa, tt.cwe)
+		if (got == base) != tt.same {
+			t.Errorf("%s: same = %v, want %v", tt.name, got == base, tt.same)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/kokuroshesh/bugvay/internal/database"
+	"github.com/kokuroshesh/bugvay/internal/oob"
+)
+
+// InteractionService is the Postgres store shared by the oob service and
+// workers. It implements oob.Store.
+type InteractionService struct {
+	pg *database.PostgresDB
+}
+
+func NewInteractionService(pg *database.PostgresDB) *InteractionService {
+	return &InteractionService{pg: pg}
+}
+
+func (s *InteractionService) RegisterToken(ctx context.Context, token string, tag oob.Tag) error {
+	var endpointID *int
+	if tag.EndpointID > 0 {
+		endpointID = &tag.EndpointID
+	}
+
+	_, err := s.pg.Pool.Exec(ctx, `
+		INSERT INTO oob_tokens (token, endpoint_id, scanner, param, location, payload)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, token, endpointID, tag.Scanner, tag.Param, tag.Location, tag.Payload)
+	if err != nil {
+		return fmt.Errorf("register token: %w", err)
+	}
+	return nil
+}
+
+// RecordInteraction stores an interaction for an issued token and confirms
+// the pending finding waiting on it, if any
+func (s *InteractionService) RecordInteraction(ctx context.Context, in oob.Interaction) (bool, error) {
+	tx, err := s.pg.Pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var findingID *int
+	err = tx.QueryRow(ctx, `
+		WITH t AS (SELECT token, finding_id FROM oob_tokens WHERE token = $1)
+		INSERT INTO oob_interactions (token, protocol, remote_addr, request, received_at)
+		SELECT token, $2, $3, $4, $5 FROM t
+		RETURNING (SELECT finding_id FROM t)
+	`, in.Token, in.Protocol, in.RemoteAddr, in.Request, in.Time).Scan(&findingID)
+	if err == pgx.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("insert interaction: %w", err)
+	}
+
+	if findingID != nil {
+		if err := confirmFinding(ctx, tx, *findingID, in); err != nil {
+			return true, err
+		}
+	}
+
+	return true, tx.Commit(ctx)
+}
+
+// Interactions returns every interaction received for tokens, oldest first
+func (s *InteractionService) Interactions(ctx context.Context, tokens []string) ([]oob.Interaction, error) {
+	rows, err := s.pg.Pool.Query(ctx, `
+		SELECT token, protocol, remote_addr, request, received_at
+		FROM oob_interactions WHERE token = ANY($1)
+		ORDER BY received_at
+	`, tokens)
+	if err != nil {
+		return nil, fmt.Errorf("query interactions: %w", err)
+	}
+	defer rows.Close()
+
+	var interactions []oob.Interaction
+	for rows.Next() {
+		var in oob.Interaction
+		if err := rows.Scan(&in.Token, &in.Protocol, &in.RemoteAddr, &in.Request, &in.Time); err != nil {
+			return nil, fmt.Errorf("scan row: %w", err)
+		}
+		interactions = append(interactions, in)
+	}
+
+	return interactions, rows.Err()
+}
+
+// AttachFinding makes a callback on any of tokens confirm the pending
+// finding. A callback that arrived before the finding was stored confirms
+// it right away.
+func (s *InteractionService) AttachFinding(ctx context.Context, findingID int, tokens []string) error {
+	tx, err := s.pg.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		UPDATE oob_tokens SET finding_id = $1 WHERE token = ANY($2)
+	`, findingID, tokens)
+	if err != nil {
+		return fmt.Errorf("attach finding: %w", err)
+	}
+
+	var in oob.Interaction
+	err = tx.QueryRow(ctx, `
+		SELECT token, protocol, remote_addr, request, received_at
+		FROM oob_interactions WHERE token = ANY($1)
+		ORDER BY received_at LIMIT 1
+	`, tokens).Scan(&in.Token, &in.Protocol, &in.RemoteAddr, &in.Request, &in.Time)
+	if err != nil && err != pgx.ErrNoRows {
+		return fmt.Errorf("query interactions: %w", err)
+	}
+	if err == nil {
+		if err := confirmFinding(ctx, tx, findingID, in); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// confirmFinding upgrades a pending finding with the callback that proves
+// it. Findings that are no longer pending are left alone. The pending
+// finding covered every parameter probed, so it is re-keyed on the one
+// parameter the token was injected into; if that parameter already has a
+// finding, the callback goes to it and the pending finding is removed.
+func confirmFinding(ctx context.Context, tx pgx.Tx, findingID int, in oob.Interaction) error {
+	var evidence map[string]interface{}
+	var proof, scanner, endpointURL string
+	var cwe int
+	var param, location, payload string
+	err := tx.QueryRow(ctx, `
+		SELECT f.evidence, f.proof, f.scanner, COALESCE(f.cwe, 0), e.url, t.param, t.location, t.payload
+		FROM findings f
+		JOIN endpoints e ON e.id = f.endpoint_id
+		JOIN oob_tokens t ON t.token = $2
+		WHERE f.id = $1 AND f.status = $3
+		FOR UPDATE OF f
+	`, findingID, in.Token, FindingPending).Scan(&evidence, &proof, &scanner, &cwe, &endpointURL, &param, &location, &payload)
+	if err == pgx.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("load pending finding: %w", err)
+	}
+
+	if evidence == nil {
+		evidence = make(map[string]interface{})
+	}
+	evidence["param"] = param
+	evidence["location"] = location
+	evidence["payload"] = payload
+	evidence["token"] = in.Token
+	evidence["interaction"] = in.Protocol
+	evidence["remote_addr"] = in.RemoteAddr
+	evidence["callback"] = in.Request
+	evidence["callback_at"] = in.Time.UTC().Format(time.RFC3339)
+
+	proof = fmt.Sprintf("%s\n\nConfirmed by %s callback for token %s (param %s, payload %s)\nFrom: %s at %s\n\n--- Callback ---\n%s",
+		proof, in.Protocol, in.Token, param, payload, in.RemoteAddr, in.Time.UTC().Format(time.RFC3339), in.Request)
+
+	hash := EvidenceHash(scanner, endpointURL, location, param, cwe)
+	var existingID int
+	err = tx.QueryRow(ctx, `
+		SELECT id FROM findings WHERE evidence_hash = $1 AND id <> $2 FOR UPDATE
+	`, hash, findingID).Scan(&existingID)
+	if err != nil && err != pgx.ErrNoRows {
+		return fmt.Errorf("query finding: %w", err)
+	}
+
+	if err == pgx.ErrNoRows {
+		_, err = tx.Exec(ctx, `
+			UPDATE findings SET status = $2, evidence = $3, proof = $4, evidence_hash = $5, last_seen = NOW()
+			WHERE id = $1
+		`, findingID, FindingConfirmed, evidence, proof, hash)
+		if err != nil {
+			return fmt.Errorf("confirm finding: %w", err)
+		}
+		return nil
+	}
+
+	// Same rule as CreateFinding: only a pending finding takes the new
+	// evidence, a settled one just counts the detection
+	_, err = tx.Exec(ctx, `
+		UPDATE findings SET
+			last_seen = NOW(),
+			occurrences = occurrences + 1,
+			evidence = CASE WHEN status = $4 THEN $2 ELSE evidence END,
+			proof = CASE WHEN status = $4 THEN $3 ELSE proof END,
+			status = CASE WHEN status = $4 THEN $5 ELSE status END
+		WHERE id = $1
+	`, existingID, evidence, proof, FindingPending, FindingConfirmed)
+	if err != nil {
+		return fmt.Errorf("merge finding: %w", err)
+	}
+	_, err = tx.Exec(ctx, `UPDATE oob_tokens SET finding_id = $1 WHERE finding_id = $2`, existingID, findingID)
+	if err != nil {
+		return fmt.Errorf("move tokens: %w", err)
+	}
+	_, err = tx.Exec(ctx, `DELETE FROM findings WHERE id = $1`, findingID)
+	if err != nil {
+		return fmt.Errorf("delete pending finding: %w", err)
+	}
+	return nil
+}
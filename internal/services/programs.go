@@ -2,11 +2,14 @@ package services
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/kokuroshesh/bugvay/internal/database"
+	"github.com/kokuroshesh/bugvay/internal/httpclient"
 )
 
 type ProgramService struct {
@@ -14,13 +17,58 @@ type ProgramService struct {
 }
 
 type Program struct {
-	ID        int       `json:"id"`
-	Name      string    `json:"name"`
-	CreatedAt time.Time `json:"created_at"`
+	ID             int                 `json:"id"`
+	Name           string              `json:"name"`
+	RateLimit      int                 `json:"rate_limit_rps,omitempty"` // 0 means worker default
+	RequestProfile *httpclient.Profile `json:"request_profile,omitempty"`
+	CreatedAt      time.Time           `json:"created_at"`
 }
 
 type CreateProgramRequest struct {
-	Name string `json:"name" binding:"required"`
+	Name           string              `json:"name" binding:"required"`
+	RateLimit      int                 `json:"rate_limit_rps"`
+	RequestProfile *httpclient.Profile `json:"request_profile"`
+}
+
+// RateLimitRequest sets or, with 0, removes a program's rate override
+type RateLimitRequest struct {
+	RateLimit int `json:"rate_limit_rps"`
+}
+
+var (
+	// ErrInvalidRateLimit is returned for a negative rate override
+	ErrInvalidRateLimit = errors.New("invalid rate limit: rate_limit_rps must be 0 (worker default) or positive")
+
+	// ErrProgramNotFound is returned when no program has the given id
+	ErrProgramNotFound = errors.New("program not found")
+)
+
+// rateLimitOverride is the rate_limit_rps column value for n, NULL meaning
+// the worker default
+func rateLimitOverride(n int) (*int, error) {
+	if n < 0 {
+		return nil, ErrInvalidRateLimit
+	}
+	if n == 0 {
+		return nil, nil
+	}
+	return &n, nil
+}
+
+const programColumns = "id, name, COALESCE(rate_limit_rps, 0), request_profile, created_at"
+
+func scanProgram(row pgx.Row) (*Program, error) {
+	var p Program
+	var profile []byte
+	if err := row.Scan(&p.ID, &p.Name, &p.RateLimit, &profile, &p.CreatedAt); err != nil {
+		return nil, err
+	}
+	if len(profile) > 0 {
+		if err := json.Unmarshal(profile, &p.RequestProfile); err != nil {
+			return nil, fmt.Errorf("decode request profile: %w", err)
+		}
+	}
+	return &p, nil
 }
 
 func NewProgramService(pg *database.PostgresDB) *ProgramService {
@@ -28,38 +76,88 @@ func NewProgramService(pg *database.PostgresDB) *ProgramService {
 }
 
 func (s *ProgramService) CreateProgram(ctx context.Context, req *CreateProgramRequest) (*Program, error) {
-	var program Program
-	err := s.pg.Pool.QueryRow(ctx, `
-		INSERT INTO programs (name) VALUES ($1)
-		RETURNING id, name, created_at
-	`, req.Name).Scan(&program.ID, &program.Name, &program.CreatedAt)
+	rateLimit, err := rateLimitOverride(req.RateLimit)
+	if err != nil {
+		return nil, err
+	}
+	if err := req.RequestProfile.Validate(); err != nil {
+		return nil, err
+	}
+
+	program, err := scanProgram(s.pg.Pool.QueryRow(ctx, `
+		INSERT INTO programs (name, rate_limit_rps, request_profile) VALUES ($1, $2, $3)
+		RETURNING `+programColumns,
+		req.Name, rateLimit, req.RequestProfile))
 
 	if err != nil {
 		return nil, fmt.Errorf("create program: %w", err)
 	}
 
-	return &program, nil
+	return program, nil
+}
+
+// SetRequestProfile replaces the program's request profile; nil removes it
+func (s *ProgramService) SetRequestProfile(ctx context.Context, id int, profile *httpclient.Profile) (*Program, error) {
+	if err := profile.Validate(); err != nil {
+		return nil, err
+	}
+
+	program, err := scanProgram(s.pg.Pool.QueryRow(ctx, `
+		UPDATE programs SET request_profile = $2 WHERE id = $1
+		RETURNING `+programColumns,
+		id, profile))
+
+	if err == pgx.ErrNoRows {
+		return nil, ErrProgramNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("update request profile: %w", err)
+	}
+
+	return program, nil
+}
+
+// SetRateLimit replaces the program's per-host rate override; 0 removes it
+// so the program falls back to WORKER_RATE_LIMIT
+func (s *ProgramService) SetRateLimit(ctx context.Context, id int, req *RateLimitRequest) (*Program, error) {
+	rateLimit, err := rateLimitOverride(req.RateLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	program, err := scanProgram(s.pg.Pool.QueryRow(ctx, `
+		UPDATE programs SET rate_limit_rps = $2 WHERE id = $1
+		RETURNING `+programColumns,
+		id, rateLimit))
+
+	if err == pgx.ErrNoRows {
+		return nil, ErrProgramNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("update rate limit: %w", err)
+	}
+
+	return program, nil
 }
 
 func (s *ProgramService) GetProgram(ctx context.Context, id int) (*Program, error) {
-	var p Program
-	err := s.pg.Pool.QueryRow(ctx, `
-		SELECT id, name, created_at FROM programs WHERE id = $1
-	`, id).Scan(&p.ID, &p.Name, &p.CreatedAt)
+	p, err := scanProgram(s.pg.Pool.QueryRow(ctx, `
+		SELECT `+programColumns+` FROM programs WHERE id = $1
+	`, id))
 
 	if err == pgx.ErrNoRows {
-		return nil, fmt.Errorf("program not found")
+		return nil, ErrProgramNotFound
 	}
 	if err != nil {
 		return nil, fmt.Errorf("query program: %w", err)
 	}
 
-	return &p, nil
+	return p, nil
 }
 
 func (s *ProgramService) ListPrograms(ctx context.Context, limit, offset int) ([]Program, error) {
 	rows, err := s.pg.Pool.Query(ctx, `
-		SELECT id, name, created_at FROM programs
+		SELECT `+programColumns+` FROM programs
 		ORDER BY created_at DESC LIMIT $1 OFFSET $2
 	`, limit, offset)
 	if err != nil {
@@ -69,11 +167,11 @@ func (s *ProgramService) ListPrograms(ctx context.Context, limit, offset int) ([
 
 	var programs []Program
 	for rows.Next() {
-		var p Program
-		if err := rows.Scan(&p.ID, &p.Name, &p.CreatedAt); err != nil {
+		p, err := scanProgram(rows)
+		if err != nil {
 			return nil, fmt.Errorf("scan row: %w", err)
 		}
-		programs = append(programs, p)
+		programs = append(programs, *p)
 	}
 
 	return programs, nil
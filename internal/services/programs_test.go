@@ -0,0 +1,30 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRateLimitOverride(t *testing.T) {
+	if got, err := rateLimitOverride(0); got != nil || err != nil {
+		t.Errorf("rateLimitOverride(0) = %v, %v; want NULL", got, err)
+	}
+	if got, err := rateLimitOverride(5); err != nil || got == nil || *got != 5 {
+		t.Errorf("rateLimitOverride(5) = %v, %v", got, err)
+	}
+	if _, err := rateLimitOverride(-1); !errors.Is(err, ErrInvalidRateLimit) {
+		t.Errorf("rateLimitOverride(-1) err = %v, want ErrInvalidRateLimit", err)
+	}
+}
+
+// Create and update reject the same values before the database is touched
+func TestProgramRejectsNegativeRateLimit(t *testing.T) {
+	s := &ProgramService{}
+	if _, err := s.CreateProgram(context.Background(), &CreateProgramRequest{Name: "p", RateLimit: -1}); !errors.Is(err, ErrInvalidRateLimit) {
+		t.Errorf("CreateProgram err = %v, want ErrInvalidRateLimit", err)
+	}
+	if _, err := s.SetRateLimit(context.Background(), 1, &RateLimitRequest{RateLimit: -1}); !errors.Is(err, ErrInvalidRateLimit) {
+		t.Errorf("SetRateLimit err = %v, want ErrInvalidRateLimit", err)
+	}
+}
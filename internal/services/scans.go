@@ -2,12 +2,17 @@ package services
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
+	"strings"
 	"time"
 
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5"
 	"github.com/kokuroshesh/bugvay/internal/database"
 	"github.com/kokuroshesh/bugvay/internal/queue"
+	"github.com/kokuroshesh/bugvay/internal/scanners"
 )
 
 type ScanService struct {
@@ -17,65 +22,209 @@ type ScanService struct {
 }
 
 type ScanRequest struct {
+	ProgramID   int      `json:"program_id"`
 	EndpointIDs []int    `json:"endpoint_ids"`
 	Scanners    []string `json:"scanners"`
 	Concurrency int      `json:"concurrency"`
 	RateLimit   int      `json:"rate_limit"`
 }
 
+// Scan statuses
+const (
+	ScanPending   = "pending"
+	ScanRunning   = "running"
+	ScanPaused    = "paused"
+	ScanCompleted = "completed"
+	ScanFailed    = "failed"
+	ScanCancelled = "cancelled"
+)
+
+// Scan job statuses
+const (
+	JobPending   = "pending"
+	JobRunning   = "running"
+	JobSuccess   = "success"
+	JobFailed    = "failed"
+	JobCancelled = "cancelled"
+)
+
+// ErrScanStateConflict is returned when a scan cannot make the requested
+// state transition, e.g. resuming a scan that is not paused
+var ErrScanStateConflict = errors.New("scan state conflict")
+
+// ErrScanNotFound is returned when no scan has the given id
+var ErrScanNotFound = errors.New("scan not found")
+
+// ErrInvalidScan is returned for scan requests that cannot be run
+var ErrInvalidScan = errors.New("invalid scan request")
+
 type Scan struct {
-	ID          string    `json:"id"`
-	ProgramID   int       `json:"program_id"`
-	Status      string    `json:"status"`
-	Scanners    []string  `json:"scanners"`
-	JobsTotal   int       `json:"jobs_total"`
-	JobsSuccess int       `json:"jobs_success"`
-	JobsFailed  int       `json:"jobs_failed"`
-	CreatedAt   time.Time `json:"created_at"`
+	ID          string     `json:"id"`
+	ProgramID   int        `json:"program_id,omitempty"`
+	Status      string     `json:"status"`
+	Scanners    []string   `json:"scanners"`
+	JobsTotal   int        `json:"jobs_total"`
+	JobsSuccess int        `json:"jobs_success"`
+	JobsFailed  int        `json:"jobs_failed"`
+	Progress    float64    `json:"progress"`
+	CreatedAt   time.Time  `json:"created_at"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
 }
 
+const scanColumns = `id, COALESCE(program_id, 0), status, scanners, jobs_total, jobs_success, jobs_failed, created_at, started_at, finished_at`
+
 func NewScanService(pg *database.PostgresDB, ch *database.ClickHouseDB, q *queue.Client) *ScanService {
 	return &ScanService{pg: pg, ch: ch, q: q}
 }
 
 func (s *ScanService) CreateScan(ctx context.Context, req *ScanRequest) (*Scan, error) {
-	// Validate scanners
-	validScanners := map[string]bool{
-		"xss": true, "sqli": true, "lfi": true, "redirect": true,
+	endpointIDs, scannerNames := uniqueInts(req.EndpointIDs), uniqueStrings(req.Scanners)
+	if len(endpointIDs) == 0 || len(scannerNames) == 0 {
+		return nil, fmt.Errorf("%w: endpoint_ids and scanners are required", ErrInvalidScan)
 	}
-
-	for _, scanner := range req.Scanners {
-		if !validScanners[scanner] {
-			return nil, fmt.Errorf("invalid scanner: %s", scanner)
+	for _, scanner := range scannerNames {
+		if _, ok := scanners.Lookup(scanner); !ok {
+			return nil, fmt.Errorf("%w: unknown scanner %s (available: %s)", ErrInvalidScan, scanner, strings.Join(scanners.Names(), ", "))
 		}
 	}
 
-	// Enqueue jobs
-	jobIDs := []string{}
-	for _, endpointID := range req.EndpointIDs {
-		for _, scanner := range req.Scanners {
-			payload, _ := json.Marshal(map[string]interface{}{
-				"endpoint_id": endpointID,
-				"scanner":     scanner,
-			})
+	endpoints, err := s.loadEndpoints(ctx, endpointIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var programID *int
+	if req.ProgramID > 0 {
+		programID = &req.ProgramID
+	}
+
+	// The scan and all of its jobs exist together or not at all, so a
+	// scan can always account for every job it counts
+	tx, err := s.pg.Pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	scanID := fmt.Sprintf("scan_%s", generateScanID())
+	_, err = tx.Exec(ctx, `
+		INSERT INTO scans (id, program_id, status, scanners, jobs_total)
+		VALUES ($1, $2, $3, $4, $5)
+	`, scanID, programID, ScanPending, scannerNames, len(endpointIDs)*len(scannerNames))
+	if err != nil {
+		return nil, fmt.Errorf("create scan: %w", err)
+	}
 
-			jobID, err := s.q.EnqueueScan(ctx, scanner, endpointID, payload)
+	type job struct {
+		taskID  string
+		payload queue.ScanPayload
+	}
+	var jobs []job
+	for _, endpoint := range endpoints {
+		for _, scanner := range scannerNames {
+			j := job{fmt.Sprintf("%s:%d:%s", scanID, endpoint.ID, scanner), scanPayload(scanID, scanner, endpoint)}
+			_, err := tx.Exec(ctx, `
+				INSERT INTO scan_jobs (task_id, scan_id, endpoint_id, scanner)
+				VALUES ($1, $2, $3, $4)
+			`, j.taskID, scanID, endpoint.ID, scanner)
 			if err != nil {
-				return nil, fmt.Errorf("enqueue job: %w", err)
+				return nil, fmt.Errorf("create scan job: %w", err)
 			}
-			jobIDs = append(jobIDs, jobID)
+			jobs = append(jobs, j)
 		}
 	}
 
-	scan := &Scan{
-		ID:        fmt.Sprintf("scan_%s", generateScanID()),
-		Status:    "running",
-		Scanners:  req.Scanners,
-		JobsTotal: len(jobIDs),
-		CreatedAt: time.Now(),
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("commit scan: %w", err)
 	}
 
-	return scan, nil
+	// Enqueued only after commit, so the scan_jobs row exists before a
+	// worker can pick the task up
+	for _, j := range jobs {
+		payload, err := queue.NewScanPayload(j.payload)
+		if err == nil {
+			_, err = s.q.EnqueueScan(ctx, j.payload.Scanner, j.payload.EndpointID, payload, asynq.TaskID(j.taskID))
+		}
+		if err != nil {
+			// Count it as failed so the scan can still finish
+			if err := s.FinishJob(ctx, j.taskID, err); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return s.GetScanStatus(ctx, scanID)
+}
+
+// loadEndpoints returns the endpoints with ids, in the order given
+func (s *ScanService) loadEndpoints(ctx context.Context, ids []int) ([]*Endpoint, error) {
+	rows, err := s.pg.Pool.Query(ctx, `SELECT `+endpointColumns+` FROM endpoints WHERE id = ANY($1)`, ids)
+	if err != nil {
+		return nil, fmt.Errorf("query endpoints: %w", err)
+	}
+	defer rows.Close()
+
+	byID := make(map[int]*Endpoint, len(ids))
+	for rows.Next() {
+		e, err := scanEndpoint(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan row: %w", err)
+		}
+		byID[e.ID] = e
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query endpoints: %w", err)
+	}
+
+	endpoints := make([]*Endpoint, len(ids))
+	for i, id := range ids {
+		if endpoints[i] = byID[id]; endpoints[i] == nil {
+			return nil, fmt.Errorf("%w: endpoint %d not found", ErrInvalidScan, id)
+		}
+	}
+	return endpoints, nil
+}
+
+// scanPayload is the task payload for scanning e with scanner. It carries
+// the endpoint's whole request, so body and header insertion points are
+// tested too.
+func scanPayload(scanID, scanner string, e *Endpoint) queue.ScanPayload {
+	return queue.ScanPayload{
+		ScanID:     scanID,
+		EndpointID: e.ID,
+		Scanner:    scanner,
+		URL:        e.URL,
+		Method:     e.Method,
+		Headers:    e.Headers,
+		Body:       e.Body,
+	}
+}
+
+// uniqueInts returns ids without duplicates, in first-seen order
+func uniqueInts(ids []int) []int {
+	seen := make(map[int]bool, len(ids))
+	var out []int
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+// uniqueStrings returns names without duplicates, in first-seen order
+func uniqueStrings(names []string) []string {
+	seen := make(map[string]bool, len(names))
+	var out []string
+	for _, name := range names {
+		if !seen[name] {
+			seen[name] = true
+			out = append(out, name)
+		}
+	}
+	return out
 }
 
 // generateScanID creates a short UUID for scan identification
@@ -85,13 +234,285 @@ func generateScanID() string {
 }
 
 func (s *ScanService) GetScanStatus(ctx context.Context, scanID string) (*Scan, error) {
-	// TODO: Query job status from Asynq/Redis
-	// For now, return mock data
-	return &Scan{
-		ID:          scanID,
-		Status:      "running",
-		JobsTotal:   100,
-		JobsSuccess: 75,
-		JobsFailed:  5,
-	}, nil
+	scan, err := scanScan(s.pg.Pool.QueryRow(ctx, `SELECT `+scanColumns+` FROM scans WHERE id = $1`, scanID))
+	if err == pgx.ErrNoRows {
+		return nil, ErrScanNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query scan: %w", err)
+	}
+
+	return scan, nil
+}
+
+func (s *ScanService) ListScans(ctx context.Context, filters map[string]interface{}, limit, offset int) ([]Scan, error) {
+	query := `SELECT ` + scanColumns + ` FROM scans WHERE 1=1`
+	args := []interface{}{}
+	argPos := 1
+
+	if status, ok := filters["status"].(string); ok && status != "" {
+		query += fmt.Sprintf(" AND status = $%d", argPos)
+		args = append(args, status)
+		argPos++
+	}
+
+	if programID, ok := filters["program_id"].(int); ok && programID > 0 {
+		query += fmt.Sprintf(" AND program_id = $%d", argPos)
+		args = append(args, programID)
+		argPos++
+	}
+
+	query += fmt.Sprintf(" ORDER BY created_at DESC LIMIT $%d OFFSET $%d", argPos, argPos+1)
+	args = append(args, limit, offset)
+
+	rows, err := s.pg.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query scans: %w", err)
+	}
+	defer rows.Close()
+
+	var scans []Scan
+	for rows.Next() {
+		scan, err := scanScan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan row: %w", err)
+		}
+		scans = append(scans, *scan)
+	}
+
+	return scans, rows.Err()
+}
+
+// StartJob marks a task as running and the scan as started on first use
+func (s *ScanService) StartJob(ctx context.Context, taskID string) error {
+	var scanID string
+	err := s.pg.Pool.QueryRow(ctx, `
+		UPDATE scan_jobs
+		SET status = $1, started_at = COALESCE(started_at, NOW())
+		WHERE task_id = $2 AND status IN ($3, $1)
+		RETURNING scan_id
+	`, JobRunning, taskID, JobPending).Scan(&scanID)
+	if err == pgx.ErrNoRows {
+		return nil // not part of a tracked scan, or already finished
+	}
+	if err != nil {
+		return fmt.Errorf("start job: %w", err)
+	}
+
+	_, err = s.pg.Pool.Exec(ctx, `
+		UPDATE scans
+		SET status = $1, started_at = COALESCE(started_at, NOW())
+		WHERE id = $2 AND status = $3
+	`, ScanRunning, scanID, ScanPending)
+	if err != nil {
+		return fmt.Errorf("start scan: %w", err)
+	}
+
+	return nil
+}
+
+// FinishJob records the final outcome of a task (jobErr nil means success)
+// and completes the scan once every job is accounted for
+func (s *ScanService) FinishJob(ctx context.Context, taskID string, jobErr error) error {
+	status, errMsg := JobSuccess, ""
+	if jobErr != nil {
+		status, errMsg = JobFailed, jobErr.Error()
+	}
+
+	tx, err := s.pg.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Only count a job once, even if Asynq delivers it again
+	var scanID string
+	err = tx.QueryRow(ctx, `
+		UPDATE scan_jobs
+		SET status = $1, error = NULLIF($2, ''), finished_at = NOW()
+		WHERE task_id = $3 AND status NOT IN ($4, $5, $6)
+		RETURNING scan_id
+	`, status, errMsg, taskID, JobSuccess, JobFailed, JobCancelled).Scan(&scanID)
+	if err == pgx.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("finish job: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE scans SET
+			jobs_success = jobs_success + CASE WHEN $1 = 'success' THEN 1 ELSE 0 END,
+			jobs_failed = jobs_failed + CASE WHEN $1 = 'failed' THEN 1 ELSE 0 END,
+			started_at = COALESCE(started_at, NOW())
+		WHERE id = $2
+	`, status, scanID)
+	if err != nil {
+		return fmt.Errorf("update scan counts: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE scans SET
+			status = CASE WHEN jobs_failed >= jobs_total THEN $1 ELSE $2 END,
+			finished_at = NOW()
+		WHERE id = $3 AND finished_at IS NULL AND jobs_success + jobs_failed >= jobs_total
+	`, ScanFailed, ScanCompleted, scanID)
+	if err != nil {
+		return fmt.Errorf("complete scan: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+// CancelScan stops a scan for good: queued tasks are deleted and running
+// tasks are cancelled through their context
+func (s *ScanService) CancelScan(ctx context.Context, scanID string) (*Scan, error) {
+	if err := s.transition(ctx, scanID, ScanCancelled, ScanPending, ScanRunning, ScanPaused); err != nil {
+		return nil, err
+	}
+
+	jobs, err := s.unfinishedJobs(ctx, scanID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, job := range jobs {
+		if job.status == JobRunning {
+			if err := s.q.CancelJob(job.taskID); err != nil {
+				log.Printf("Failed to cancel task %s: %v", job.taskID, err)
+			}
+		}
+		// Running tasks may already be waiting for a retry
+		if err := s.q.DeleteJob(job.queue, job.taskID); err != nil && job.status != JobRunning {
+			log.Printf("Failed to delete task %s: %v", job.taskID, err)
+		}
+	}
+
+	_, err = s.pg.Pool.Exec(ctx, `
+		UPDATE scan_jobs SET status = $1, finished_at = NOW()
+		WHERE scan_id = $2 AND status IN ($3, $4)
+	`, JobCancelled, scanID, JobPending, JobRunning)
+	if err != nil {
+		return nil, fmt.Errorf("cancel scan jobs: %w", err)
+	}
+
+	return s.GetScanStatus(ctx, scanID)
+}
+
+// PauseScan archives the scan's queued tasks. Tasks already running are
+// allowed to finish, so no progress is lost.
+func (s *ScanService) PauseScan(ctx context.Context, scanID string) (*Scan, error) {
+	if err := s.transition(ctx, scanID, ScanPaused, ScanPending, ScanRunning); err != nil {
+		return nil, err
+	}
+
+	jobs, err := s.unfinishedJobs(ctx, scanID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, job := range jobs {
+		if job.status != JobPending {
+			continue
+		}
+		// Tasks picked up in the meantime are archived by the worker instead
+		if err := s.q.ArchiveJob(job.queue, job.taskID); err != nil {
+			log.Printf("Failed to archive task %s: %v", job.taskID, err)
+		}
+	}
+
+	return s.GetScanStatus(ctx, scanID)
+}
+
+// ResumeScan puts the archived tasks of a paused scan back in the queue
+func (s *ScanService) ResumeScan(ctx context.Context, scanID string) (*Scan, error) {
+	if err := s.transition(ctx, scanID, ScanRunning, ScanPaused); err != nil {
+		return nil, err
+	}
+
+	jobs, err := s.unfinishedJobs(ctx, scanID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Running jobs may have failed into the retry set and been archived by
+	// the worker while paused; RunJob is a no-op error for active tasks
+	for _, job := range jobs {
+		if err := s.q.RunJob(job.queue, job.taskID); err != nil {
+			// Still pending if the pause raced with the enqueue
+			log.Printf("Failed to resume task %s: %v", job.taskID, err)
+		}
+	}
+
+	return s.GetScanStatus(ctx, scanID)
+}
+
+// transition moves a scan to status if it is currently in one of from
+func (s *ScanService) transition(ctx context.Context, scanID, status string, from ...string) error {
+	tag, err := s.pg.Pool.Exec(ctx, `
+		UPDATE scans SET
+			status = $1,
+			finished_at = CASE WHEN $1 = 'cancelled' THEN NOW() ELSE finished_at END
+		WHERE id = $2 AND status = ANY($3)
+	`, status, scanID, from)
+	if err != nil {
+		return fmt.Errorf("update scan status: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		scan, err := s.GetScanStatus(ctx, scanID)
+		if err != nil {
+			return err
+		}
+		return fmt.Errorf("%w: cannot move scan from %s to %s", ErrScanStateConflict, scan.Status, status)
+	}
+
+	return nil
+}
+
+type scanJob struct {
+	taskID string
+	queue  string
+	status string
+}
+
+func (s *ScanService) unfinishedJobs(ctx context.Context, scanID string) ([]scanJob, error) {
+	rows, err := s.pg.Pool.Query(ctx, `
+		SELECT task_id, scanner, status FROM scan_jobs
+		WHERE scan_id = $1 AND status IN ($2, $3)
+	`, scanID, JobPending, JobRunning)
+	if err != nil {
+		return nil, fmt.Errorf("query scan jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var jobs []scanJob
+	for rows.Next() {
+		var job scanJob
+		var scanner string
+		if err := rows.Scan(&job.taskID, &scanner, &job.status); err != nil {
+			return nil, fmt.Errorf("scan row: %w", err)
+		}
+
+		job.queue = "default"
+		if reg, ok := scanners.Lookup(scanner); ok {
+			job.queue = reg.Queue
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, rows.Err()
+}
+
+func scanScan(row pgx.Row) (*Scan, error) {
+	var sc Scan
+	if err := row.Scan(&sc.ID, &sc.ProgramID, &sc.Status, &sc.Scanners, &sc.JobsTotal,
+		&sc.JobsSuccess, &sc.JobsFailed, &sc.CreatedAt, &sc.StartedAt, &sc.FinishedAt); err != nil {
+		return nil, err
+	}
+
+	if sc.JobsTotal > 0 {
+		sc.Progress = float64(sc.JobsSuccess+sc.JobsFailed) / float64(sc.JobsTotal)
+	}
+	return &sc, nil
 }
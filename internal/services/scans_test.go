@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/kokuroshesh/bugvay/internal/queue"
+	"github.com/kokuroshesh/bugvay/internal/scanners"
+)
+
+func TestUniqueInts(t *testing.T) {
+	tests := []struct {
+		in, want []int
+	}{
+		{nil, nil},
+		{[]int{1, 2, 3}, []int{1, 2, 3}},
+		{[]int{3, 1, 3, 2, 1}, []int{3, 1, 2}},
+	}
+	for _, tt := range tests {
+		if got := uniqueInts(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("uniqueInts(%v) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestUniqueStrings(t *testing.T) {
+	got := uniqueStrings([]string{"xss", "sqli", "xss"})
+	if want := []string{"xss", "sqli"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("uniqueStrings = %v, want %v", got, want)
+	}
+}
+
+// Invalid requests are rejected before the database is touched
+func TestCreateScanRejectsInvalid(t *testing.T) {
+	s := &ScanService{}
+	tests := []struct {
+		name string
+		req  ScanRequest
+	}{
+		{"no endpoints", ScanRequest{Scanners: []string{"xss"}}},
+		{"no scanners", ScanRequest{EndpointIDs: []int{1}}},
+		{"unknown scanner", ScanRequest{EndpointIDs: []int{1}, Scanners: []string{"nope"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.CreateScan(context.Background(), &tt.req); !errors.Is(err, ErrInvalidScan) {
+				t.Errorf("err = %v, want ErrInvalidScan", err)
+			}
+		})
+	}
+}
+
+// An endpoint imported with a body is scanned with it: the task payload
+// carries the request, and the worker's input has body insertion points
+func TestScanPayloadCarriesRequest(t *testing.T) {
+	e := &Endpoint{
+		ID:      7,
+		URL:     "https://example.com/api/users?debug=1",
+		Method:  "POST",
+		Headers: map[string]string{"Content-Type": "application/json", "Cookie": "session=abc"},
+		Body:    `{"name":"alice","address":{"city":"Oslo"}}`,
+	}
+
+	raw, err := queue.NewScanPayload(scanPayload("scan_1", "xss", e))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var payload queue.ScanPayload
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		t.Fatal(err)
+	}
+
+	input := &scanners.ScanInput{
+		EndpointID: payload.EndpointID,
+		URL:        e.URL,
+		Method:     payload.Method,
+		Headers:    payload.Headers,
+		Body:       payload.Body,
+	}
+	points, err := input.InsertionPoints()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string]bool)
+	for _, p := range points {
+		got[p.Location+" "+p.Name] = true
+	}
+	for _, want := range []string{"query debug", "body_json name", "body_json address.city", "cookie session"} {
+		if !got[want] {
+			t.Errorf("missing insertion point %q, got %v", want, got)
+		}
+	}
+
+	req, err := input.NewRequest(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Method != "POST" || req.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("request = %s with Content-Type %q", req.Method, req.Header.Get("Content-Type"))
+	}
+}
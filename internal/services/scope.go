@@ -0,0 +1,160 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/kokuroshesh/bugvay/internal/database"
+	"github.com/kokuroshesh/bugvay/internal/scope"
+)
+
+type ScopeService struct {
+	pg *database.PostgresDB
+}
+
+type ScopeRule struct {
+	ID        int       `json:"id"`
+	ProgramID int       `json:"program_id"`
+	Kind      string    `json:"kind"`
+	Type      string    `json:"type"`
+	Pattern   string    `json:"pattern"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CreateScopeRuleRequest struct {
+	Kind    string `json:"kind" binding:"required"` // in, out
+	Type    string `json:"type" binding:"required"` // host, cidr, path, param
+	Pattern string `json:"pattern" binding:"required"`
+}
+
+func NewScopeService(pg *database.PostgresDB) *ScopeService {
+	return &ScopeService{pg: pg}
+}
+
+func (s *ScopeService) CreateRule(ctx context.Context, programID int, req *CreateScopeRuleRequest) (*ScopeRule, error) {
+	rule := scope.Rule{Kind: req.Kind, Type: req.Type, Pattern: strings.TrimSpace(req.Pattern)}
+	if err := rule.Validate(); err != nil {
+		return nil, err
+	}
+
+	var r ScopeRule
+	err := s.pg.Pool.QueryRow(ctx, `
+		INSERT INTO scope_rules (program_id, kind, type, pattern)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, program_id, kind, type, pattern, created_at
+	`, programID, rule.Kind, rule.Type, rule.Pattern).Scan(
+		&r.ID, &r.ProgramID, &r.Kind, &r.Type, &r.Pattern, &r.CreatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("create scope rule: %w", err)
+	}
+
+	return &r, nil
+}
+
+func (s *ScopeService) ListRules(ctx context.Context, programID int) ([]ScopeRule, error) {
+	rows, err := s.pg.Pool.Query(ctx, `
+		SELECT id, program_id, kind, type, pattern, created_at
+		FROM scope_rules WHERE program_id = $1
+		ORDER BY id
+	`, programID)
+	if err != nil {
+		return nil, fmt.Errorf("query scope rules: %w", err)
+	}
+	defer rows.Close()
+
+	var rules []ScopeRule
+	for rows.Next() {
+		var r ScopeRule
+		if err := rows.Scan(&r.ID, &r.ProgramID, &r.Kind, &r.Type, &r.Pattern, &r.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan row: %w", err)
+		}
+		rules = append(rules, r)
+	}
+
+	return rules, nil
+}
+
+func (s *ScopeService) DeleteRule(ctx context.Context, programID, ruleID int) error {
+	result, err := s.pg.Pool.Exec(ctx, "DELETE FROM scope_rules WHERE id = $1 AND program_id = $2", ruleID, programID)
+	if err != nil {
+		return fmt.Errorf("delete scope rule: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("scope rule not found")
+	}
+
+	return nil
+}
+
+// LoadForAsset compiles the scope of the program owning an asset
+func (s *ScopeService) LoadForAsset(ctx context.Context, assetID int) (*scope.Scope, error) {
+	var programID int
+	err := s.pg.Pool.QueryRow(ctx, "SELECT program_id FROM assets WHERE id = $1", assetID).Scan(&programID)
+	if err == pgx.ErrNoRows {
+		return nil, fmt.Errorf("asset not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query asset: %w", err)
+	}
+
+	return s.Load(ctx, programID)
+}
+
+// Load compiles a program's scope from its explicit rules plus its assets,
+// which count as in-scope hosts
+func (s *ScopeService) Load(ctx context.Context, programID int) (*scope.Scope, error) {
+	var rules []scope.Rule
+
+	stored, err := s.ListRules(ctx, programID)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range stored {
+		rules = append(rules, scope.Rule{Kind: r.Kind, Type: r.Type, Pattern: r.Pattern})
+	}
+
+	rows, err := s.pg.Pool.Query(ctx, "SELECT domain, type FROM assets WHERE program_id = $1", programID)
+	if err != nil {
+		return nil, fmt.Errorf("query assets: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var domain, assetType string
+		if err := rows.Scan(&domain, &assetType); err != nil {
+			return nil, fmt.Errorf("scan row: %w", err)
+		}
+		rules = append(rules, assetRules(domain, assetType)...)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query assets: %w", err)
+	}
+
+	return scope.New(programID, rules)
+}
+
+// assetRules turns an asset into in-scope host rules
+func assetRules(domain, assetType string) []scope.Rule {
+	domain = strings.ToLower(strings.TrimSpace(domain))
+
+	switch assetType {
+	case "wildcard":
+		apex := strings.TrimPrefix(domain, "*.")
+		return []scope.Rule{
+			{Kind: scope.InScope, Type: scope.TypeHost, Pattern: apex},
+			{Kind: scope.InScope, Type: scope.TypeHost, Pattern: "*." + apex},
+		}
+	case "url":
+		if u, err := url.Parse(domain); err == nil && u.Hostname() != "" {
+			domain = u.Hostname()
+		}
+	}
+
+	return []scope.Rule{{Kind: scope.InScope, Type: scope.TypeHost, Pattern: domain}}
+}
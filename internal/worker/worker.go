@@ -3,18 +3,25 @@ package worker
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/hibiken/asynq"
 	"github.com/kokuroshesh/bugvay/internal/config"
 	"github.com/kokuroshesh/bugvay/internal/database"
 	"github.com/kokuroshesh/bugvay/internal/httpclient"
+	"github.com/kokuroshesh/bugvay/internal/oob"
 	"github.com/kokuroshesh/bugvay/internal/queue"
+	"github.com/kokuroshesh/bugvay/internal/ratelimit"
+	"github.com/kokuroshesh/bugvay/internal/recorder"
 	"github.com/kokuroshesh/bugvay/internal/scanners"
-	"github.com/kokuroshesh/bugvay/internal/scanners/xss"
+	"github.com/kokuroshesh/bugvay/internal/scope"
 	"github.com/kokuroshesh/bugvay/internal/services"
+	"github.com/redis/go-redis/v9"
 )
 
 type Worker struct {
@@ -25,9 +32,29 @@ type Worker struct {
 	httpClient      *httpclient.Scanner
 	findingService  *services.FindingService
 	endpointService *services.EndpointService
+	scanService     *services.ScanService
+	scopeService    *services.ScopeService
+	programService  *services.ProgramService
+	rdb             *redis.Client
+	recorder        *recorder.Recorder
+	interactions    *services.InteractionService
+	oob             *oob.Client
+	oobServer       *oob.Server // embedded listeners, if any
 }
 
-func NewWorker(cfg *config.Config, pg *database.PostgresDB, ch *database.ClickHouseDB) *Worker {
+func NewWorker(cfg *config.Config, pg *database.PostgresDB, ch *database.ClickHouseDB) (*Worker, error) {
+	queues := map[string]int{
+		"critical": 6,
+		"default":  3,
+		"low":      1,
+	}
+	// Scanners may register their own queue
+	for _, reg := range scanners.All() {
+		if _, ok := queues[reg.Queue]; !ok {
+			queues[reg.Queue] = 1
+		}
+	}
+
 	srv := asynq.NewServer(
 		asynq.RedisClientOpt{
 			Addr:     cfg.Redis.Addr(),
@@ -36,17 +63,49 @@ func NewWorker(cfg *config.Config, pg *database.PostgresDB, ch *database.ClickHo
 		},
 		asynq.Config{
 			Concurrency: cfg.Worker.Concurrency,
-			Queues: map[string]int{
-				"critical": 6,
-				"default":  3,
-				"low":      1,
-			},
+			Queues:      queues,
 		},
 	)
 
-	httpClient := httpclient.NewScanner(cfg.Worker.RateLimit, time.Duration(cfg.Scanner.Timeout)*time.Second)
+	// Rate state lives in Redis so every worker process shares each host's budget
+	rdb := redis.NewClient(&redis.Options{
+		Addr:     cfg.Redis.Addr(),
+		Password: cfg.Redis.Password,
+		DB:       cfg.Redis.DB,
+	})
+	limiter := ratelimit.New(rdb, cfg.Worker.RateLimit)
+
+	httpClient := httpclient.NewScanner(limiter, cfg.Scanner)
 	findingService := services.NewFindingService(pg, ch)
 	endpointService := services.NewEndpointService(pg, ch, nil)
+	scanService := services.NewScanService(pg, ch, nil)
+
+	// ClickHouse is optional; without it probes are simply not recorded
+	var rec *recorder.Recorder
+	if ch != nil {
+		rec = recorder.New(ch)
+		httpClient.SetRecorder(rec)
+	}
+
+	// The interaction service is optional; scanners that need it skip
+	// their tasks without it. cmd/oob normally runs the listeners, but a
+	// worker can run them itself for local testing. Once enabled it must
+	// be reachable, or every callback payload would be silently dead.
+	interactions := services.NewInteractionService(pg)
+	var oobServer *oob.Server
+	var oobClient *oob.Client
+	if cfg.OOB.Embedded || cfg.OOB.PublicHost != "" {
+		if err := cfg.OOB.Validate(); err != nil {
+			return nil, fmt.Errorf("interaction service: %w", err)
+		}
+		if cfg.OOB.Embedded {
+			oobServer = oob.NewServer(cfg.OOB, interactions)
+			if err := oobServer.Start(); err != nil {
+				return nil, fmt.Errorf("embedded interaction server: %w", err)
+			}
+		}
+		oobClient = oob.NewClient(cfg.OOB, interactions)
+	}
 
 	w := &Worker{
 		server:          srv,
@@ -56,23 +115,109 @@ func NewWorker(cfg *config.Config, pg *database.PostgresDB, ch *database.ClickHo
 		httpClient:      httpClient,
 		findingService:  findingService,
 		endpointService: endpointService,
+		scanService:     scanService,
+		scopeService:    services.NewScopeService(pg),
+		programService:  services.NewProgramService(pg),
+		rdb:             rdb,
+		recorder:        rec,
+		interactions:    interactions,
+		oob:             oobClient,
+		oobServer:       oobServer,
 	}
 
 	w.registerHandlers()
-	return w
+	return w, nil
 }
 
 func (w *Worker) registerHandlers() {
-	w.mux.HandleFunc(queue.TypeScanXSS, w.handleXSSScan)
-	w.mux.HandleFunc(queue.TypeScanSQLi, w.handleSQLiScan)
-	w.mux.HandleFunc(queue.TypeScanLFI, w.handleLFIScan)
-	w.mux.HandleFunc(queue.TypeScanRedirect, w.handleRedirectScan)
+	for _, reg := range scanners.All() {
+		w.mux.HandleFunc(reg.TaskType, w.handleScan)
+	}
+}
+
+// TaskOutcome is written as the Asynq task result so operators can see
+// what each task did without digging through worker logs
+type TaskOutcome struct {
+	Scanner      string  `json:"scanner"`
+	EndpointID   int     `json:"endpoint_id"`
+	URL          string  `json:"url"`
+	Vulnerable   bool    `json:"vulnerable"`
+	Pending      bool    `json:"pending,omitempty"` // awaiting an out-of-band callback
+	Severity     string  `json:"severity,omitempty"`
+	Confidence   float64 `json:"confidence,omitempty"`
+	FindingSaved bool    `json:"finding_saved"`
+	DurationMs   int64   `json:"duration_ms"`
 }
 
-func (w *Worker) handleXSSScan(ctx context.Context, task *asynq.Task) error {
+// handleScan runs any registered scanner and keeps the owning scan's job
+// accounting up to date
+func (w *Worker) handleScan(ctx context.Context, task *asynq.Task) error {
 	var payload queue.ScanPayload
 	if err := json.Unmarshal(task.Payload(), &payload); err != nil {
-		return fmt.Errorf("unmarshal payload: %w", err)
+		return fmt.Errorf("unmarshal payload: %v: %w", err, asynq.SkipRetry)
+	}
+
+	taskID, _ := asynq.GetTaskID(ctx)
+	if payload.ScanID != "" {
+		switch w.scanState(ctx, payload.ScanID) {
+		case services.ScanCancelled:
+			log.Printf("Skipping job %s: scan %s cancelled", taskID, payload.ScanID)
+			return nil
+		case services.ScanPaused:
+			// SkipRetry archives the task; ResumeScan runs it again
+			return fmt.Errorf("scan %s paused: %w", payload.ScanID, asynq.SkipRetry)
+		}
+
+		if err := w.scanService.StartJob(ctx, taskID); err != nil {
+			log.Printf("Failed to mark job %s started: %v", taskID, err)
+		}
+	}
+
+	err := w.runScan(ctx, task, &payload)
+
+	// Cancelled through the inspector: the scan already accounted for it
+	if err != nil && ctx.Err() != nil && payload.ScanID != "" &&
+		w.scanState(context.WithoutCancel(ctx), payload.ScanID) == services.ScanCancelled {
+		return nil
+	}
+
+	// Retried tasks are only counted once they succeed or run out of retries
+	if payload.ScanID != "" && (err == nil || isFinalAttempt(ctx, err)) {
+		if err := w.scanService.FinishJob(context.WithoutCancel(ctx), taskID, err); err != nil {
+			log.Printf("Failed to record job %s outcome: %v", taskID, err)
+		}
+	}
+
+	return err
+}
+
+func (w *Worker) scanState(ctx context.Context, scanID string) string {
+	scan, err := w.scanService.GetScanStatus(ctx, scanID)
+	if err != nil {
+		log.Printf("Failed to load scan %s: %v", scanID, err)
+		return ""
+	}
+	return scan.Status
+}
+
+func isFinalAttempt(ctx context.Context, err error) bool {
+	if errors.Is(err, asynq.SkipRetry) {
+		return true
+	}
+	retried, _ := asynq.GetRetryCount(ctx)
+	maxRetry, _ := asynq.GetMaxRetry(ctx)
+	return retried >= maxRetry
+}
+
+// runScan resolves the scanner named in the payload, loads the endpoint,
+// scans it and persists the finding
+func (w *Worker) runScan(ctx context.Context, task *asynq.Task, payload *queue.ScanPayload) error {
+	reg, ok := scanners.Lookup(payload.Scanner)
+	if !ok {
+		return fmt.Errorf("unknown scanner %q: %w", payload.Scanner, asynq.SkipRetry)
+	}
+	if reg.TaskType != task.Type() {
+		return fmt.Errorf("scanner %q does not handle %s: %w", reg.Name, task.Type(), asynq.SkipRetry)
 	}
 
 	// Get endpoint details
@@ -81,55 +226,154 @@ func (w *Worker) handleXSSScan(ctx context.Context, task *asynq.Task) error {
 		return fmt.Errorf("get endpoint: %w", err)
 	}
 
-	// Run XSS scanner
-	scanner := xss.New(w.httpClient)
+	// Scope may have changed since the endpoint was ingested. Attaching it
+	// to ctx makes httpclient refuse every out-of-scope request.
+	sc, err := w.scopeService.LoadForAsset(ctx, endpoint.AssetID)
+	if err != nil {
+		return fmt.Errorf("load scope: %w", err)
+	}
+	endpointURL, err := url.Parse(endpoint.URL)
+	if err != nil {
+		return fmt.Errorf("parse endpoint url: %v: %w", err, asynq.SkipRetry)
+	}
+	if err := sc.Check(ctx, endpointURL); err != nil {
+		return fmt.Errorf("endpoint %d: %v: %w", endpoint.ID, err, asynq.SkipRetry)
+	}
+	ctx = scope.WithContext(ctx, sc)
+
+	program, err := w.programService.GetProgram(ctx, sc.ProgramID)
+	if err != nil {
+		return fmt.Errorf("get program: %w", err)
+	}
+	ctx = ratelimit.WithRate(ctx, program.RateLimit)
+	ctx = httpclient.WithProfile(ctx, program.RequestProfile)
+	if w.oob != nil {
+		ctx = oob.WithContext(ctx, w.oob)
+	}
+	ctx = recorder.WithTask(ctx, recorder.Task{
+		ProgramID:  sc.ProgramID,
+		AssetID:    endpoint.AssetID,
+		EndpointID: endpoint.ID,
+		Scanner:    reg.Name,
+	})
+
+	// Tasks queued before endpoints stored their request carry none
+	method, headers, body := payload.Method, payload.Headers, payload.Body
+	if method == "" {
+		method, headers, body = endpoint.Method, endpoint.Headers, endpoint.Body
+	}
+	if method == "" {
+		method = "GET"
+	}
+
+	start := time.Now()
+	scanner := reg.New(w.httpClient)
 	result, err := scanner.Scan(ctx, &scanners.ScanInput{
-		EndpointID: payload.EndpointID,
-		URL:        endpoint.URL,
-		Method:     "GET",
+		EndpointID:     payload.EndpointID,
+		URL:            endpoint.URL,
+		Method:         method,
+		Headers:        headers,
+		Body:           body,
+		ExcludedParams: sc.ExcludedParams(),
 	})
+	if errors.Is(err, oob.ErrUnavailable) {
+		return fmt.Errorf("%s: %v: %w", reg.Name, err, asynq.SkipRetry)
+	}
 	if err != nil {
+		log.Printf("[%s] endpoint %d: scan failed after %s: %v", reg.Name, payload.EndpointID, time.Since(start), err)
 		// Return error so Asynq can retry
 		return fmt.Errorf("scan failed: %w", err)
 	}
 
-	// Save finding if vulnerable
-	if result.Vulnerable {
+	outcome := TaskOutcome{
+		Scanner:    reg.Name,
+		EndpointID: payload.EndpointID,
+		URL:        endpoint.URL,
+		Vulnerable: result.Vulnerable,
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+
+	// Save finding if vulnerable, or pending until a callback confirms it
+	pending := !result.Vulnerable && len(result.Pending) > 0
+	if result.Vulnerable || pending {
+		cwe := result.CWE
+		if cwe == 0 {
+			cwe = reg.CWE
+		}
+
+		status := "new"
+		if pending {
+			status = services.FindingPending
+		}
 		finding := &services.Finding{
 			EndpointID: payload.EndpointID,
-			Scanner:    "xss",
+			Scanner:    reg.Name,
 			Severity:   result.Severity,
-			CWE:        result.CWE,
+			CWE:        cwe,
 			Evidence:   result.Evidence,
-			Proof:      result.Proof,
-			Status:     "new",
+			Proof:      withExchange(result.Proof, result.Response),
+			Status:     status,
 		}
 
 		if err := w.findingService.CreateFinding(ctx, finding); err != nil {
 			log.Printf("Failed to save finding: %v", err)
 			// Don't fail task if finding save fails (already scanned)
+		} else {
+			outcome.FindingSaved = true
+			if finding.Status == services.FindingPending {
+				if err := w.interactions.AttachFinding(ctx, finding.ID, result.Pending); err != nil {
+					log.Printf("Failed to attach callbacks to finding %d: %v", finding.ID, err)
+				}
+			}
+		}
+
+		if pending {
+			outcome.Pending = true
+			log.Printf("[%s] endpoint %d: finding %d pending %d callbacks", reg.Name, payload.EndpointID, finding.ID, len(result.Pending))
+		} else {
+			if w.recorder != nil {
+				testURL, _ := result.Evidence["url"].(string)
+				payload, _ := result.Evidence["payload"].(string)
+				w.recorder.RecordFinding(ctx, method, testURL, payload, result.Confidence, finding.EvidenceHash, result.Evidence)
+			}
+
+			outcome.Severity = result.Severity
+			outcome.Confidence = result.Confidence
+			log.Printf("[%s] endpoint %d: %s finding (confidence %.2f)", reg.Name, payload.EndpointID, result.Severity, result.Confidence)
 		}
 	}
 
-	return nil
-}
+	if b, err := json.Marshal(outcome); err == nil {
+		if _, err := task.ResultWriter().Write(b); err != nil {
+			log.Printf("Failed to write task result: %v", err)
+		}
+	}
 
-func (w *Worker) handleSQLiScan(ctx context.Context, task *asynq.Task) error {
-	// TODO: Implement SQLi scanner
-	log.Println("SQLi scan not implemented yet")
 	return nil
 }
 
-func (w *Worker) handleLFIScan(ctx context.Context, task *asynq.Task) error {
-	// TODO: Implement LFI scanner
-	log.Println("LFI scan not implemented yet")
-	return nil
-}
+// maxProofResponse caps the raw response kept in a finding's proof
+const maxProofResponse = 16 << 10
 
-func (w *Worker) handleRedirectScan(ctx context.Context, task *asynq.Task) error {
-	// TODO: Implement redirect scanner
-	log.Println("Redirect scan not implemented yet")
-	return nil
+// withExchange appends the raw request and response that proved a finding
+func withExchange(proof string, resp *httpclient.Response) string {
+	if resp == nil || len(resp.RawRequest) == 0 {
+		return proof
+	}
+
+	raw := resp.RawResponse
+	note := ""
+	if len(raw) > maxProofResponse {
+		raw = raw[:maxProofResponse]
+		note = "\n[truncated]"
+	} else if resp.Truncated {
+		note = "\n[body truncated by scanner]"
+	}
+	exchange := fmt.Sprintf("%s\n\n--- Request ---\n%s\n\n--- Response (%dms) ---\n%s%s",
+		proof, resp.RawRequest, resp.Elapsed.Milliseconds(), raw, note)
+
+	// Postgres text rejects NUL bytes and invalid UTF-8
+	return strings.ToValidUTF8(strings.ReplaceAll(exchange, "\x00", ""), "\uFFFD")
 }
 
 func (w *Worker) Run() error {
@@ -138,4 +382,11 @@ func (w *Worker) Run() error {
 
 func (w *Worker) Shutdown() {
 	w.server.Shutdown()
+	if w.oobServer != nil {
+		w.oobServer.Close()
+	}
+	if w.recorder != nil {
+		w.recorder.Close()
+	}
+	w.rdb.Close()
 }
@@ -0,0 +1,35 @@
+-- Persistent scans and their Asynq jobs
+
+CREATE TABLE IF NOT EXISTS scans (
+    id TEXT PRIMARY KEY,
+    program_id INT REFERENCES programs(id) ON DELETE SET NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    scanners TEXT[] NOT NULL DEFAULT '{}',
+    jobs_total INT NOT NULL DEFAULT 0,
+    jobs_success INT NOT NULL DEFAULT 0,
+    jobs_failed INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    started_at TIMESTAMP,
+    finished_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_scans_created_at ON scans(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_scans_status ON scans(status);
+
+-- One row per enqueued task; task_id is the Asynq task ID
+CREATE TABLE IF NOT EXISTS scan_jobs (
+    task_id TEXT PRIMARY KEY,
+    scan_id TEXT NOT NULL REFERENCES scans(id) ON DELETE CASCADE,
+    endpoint_id INT NOT NULL,
+    scanner TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    error TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    started_at TIMESTAMP,
+    finished_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_scan_jobs_scan_status ON scan_jobs(scan_id, status);
+
+COMMENT ON TABLE scans IS 'Scan runs with job accounting';
+COMMENT ON TABLE scan_jobs IS 'Asynq tasks belonging to a scan';
@@ -0,0 +1,15 @@
+-- Per-program in-scope and out-of-scope rules
+
+CREATE TABLE IF NOT EXISTS scope_rules (
+    id SERIAL PRIMARY KEY,
+    program_id INT NOT NULL REFERENCES programs(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL CHECK (kind IN ('in', 'out')),
+    type TEXT NOT NULL CHECK (type IN ('host', 'cidr', 'path', 'param')),
+    pattern TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (program_id, kind, type, pattern)
+);
+
+CREATE INDEX IF NOT EXISTS idx_scope_rules_program ON scope_rules(program_id);
+
+COMMENT ON TABLE scope_rules IS 'Program scope: host globs, CIDRs, path prefixes and excluded parameters';
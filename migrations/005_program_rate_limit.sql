@@ -0,0 +1,6 @@
+-- Per-program request rate override (requests per second per host).
+-- NULL falls back to WORKER_RATE_LIMIT.
+
+ALTER TABLE programs ADD COLUMN IF NOT EXISTS rate_limit_rps INT CHECK (rate_limit_rps > 0);
+
+COMMENT ON COLUMN programs.rate_limit_rps IS 'Max requests per second per host, shared by all workers';
@@ -0,0 +1,11 @@
+-- Finding deduplication on a stable evidence fingerprint
+
+ALTER TABLE findings ADD COLUMN IF NOT EXISTS evidence_hash CHAR(32);
+ALTER TABLE findings ADD COLUMN IF NOT EXISTS occurrences INT NOT NULL DEFAULT 1;
+ALTER TABLE findings ADD COLUMN IF NOT EXISTS last_seen TIMESTAMP NOT NULL DEFAULT NOW();
+
+-- Rows created before fingerprinting keep a NULL hash and are never merged
+CREATE UNIQUE INDEX IF NOT EXISTS idx_findings_evidence_hash ON findings(evidence_hash)
+WHERE evidence_hash IS NOT NULL;
+
+COMMENT ON COLUMN findings.evidence_hash IS 'sha256(scanner, canonical endpoint, parameter, vulnerability class), 32 hex chars';
@@ -0,0 +1,6 @@
+-- Per-program request profile applied to every probe: identification
+-- headers, cookies, bearer token or basic auth. NULL sends probes as-is.
+
+ALTER TABLE programs ADD COLUMN IF NOT EXISTS request_profile JSONB;
+
+COMMENT ON COLUMN programs.request_profile IS 'Headers, cookies and credentials sent with every scanner request';
@@ -0,0 +1,29 @@
+-- Out-of-band callbacks: tokens handed out by scanners and the HTTP, DNS and
+-- SMTP interactions the oob service received for them
+
+CREATE TABLE IF NOT EXISTS oob_tokens (
+    token TEXT PRIMARY KEY,
+    endpoint_id INT REFERENCES endpoints(id) ON DELETE CASCADE,
+    scanner TEXT NOT NULL,
+    param TEXT NOT NULL DEFAULT '',
+    location TEXT NOT NULL DEFAULT '',
+    payload TEXT NOT NULL DEFAULT '',
+    finding_id INT REFERENCES findings(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_oob_tokens_finding ON oob_tokens(finding_id) WHERE finding_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_oob_tokens_created_at ON oob_tokens(created_at);
+
+CREATE TABLE IF NOT EXISTS oob_interactions (
+    id BIGSERIAL PRIMARY KEY,
+    token TEXT NOT NULL REFERENCES oob_tokens(token) ON DELETE CASCADE,
+    protocol TEXT NOT NULL CHECK (protocol IN ('http', 'dns', 'smtp')),
+    remote_addr TEXT NOT NULL DEFAULT '',
+    request TEXT NOT NULL DEFAULT '',
+    received_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_oob_interactions_token ON oob_interactions(token, received_at);
+
+COMMENT ON COLUMN oob_tokens.finding_id IS 'Pending finding confirmed when the first interaction for the token arrives';
@@ -0,0 +1,4 @@
+-- The evidence fingerprint now includes the parameter location. Findings
+-- stored earlier keep their old hash; a re-detection creates a new finding.
+
+COMMENT ON COLUMN findings.evidence_hash IS 'sha256(scanner, canonical endpoint, parameter location, parameter, vulnerability class), 32 hex chars';
@@ -0,0 +1,8 @@
+-- Endpoints keep the request they are scanned with, so bodies and headers
+-- reach the scanners. Existing endpoints are plain GETs.
+
+ALTER TABLE endpoints ADD COLUMN IF NOT EXISTS method TEXT NOT NULL DEFAULT 'GET';
+ALTER TABLE endpoints ADD COLUMN IF NOT EXISTS headers JSONB NOT NULL DEFAULT '{}';
+ALTER TABLE endpoints ADD COLUMN IF NOT EXISTS body TEXT NOT NULL DEFAULT '';
+
+COMMENT ON COLUMN endpoints.hash IS 'Hash of the canonical URL, prefixed with method and body unless a plain GET';